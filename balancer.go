@@ -0,0 +1,412 @@
+package pantopoda
+
+import (
+	"context"
+	"errors"
+	"hash/crc32"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Kamva/pantopoda/compress"
+)
+
+// ErrNoEndpoints is returned when the resolver of the balancer resolves no
+// endpoints.
+var ErrNoEndpoints = errors.New("pantopoda: no endpoints resolved")
+
+// BalancerConfig configures the client-side load balancing of requests
+// between the instances of a service.
+type BalancerConfig struct {
+	// Resolver resolves the base URLs of the instances.
+	Resolver Resolver
+
+	// Strategy picks the instance of each request, RoundRobin by default.
+	Strategy Strategy
+
+	// MaxFailures is the number of consecutive failures, i.e. transport
+	// errors and server errors, after which an instance is ejected, 5 by
+	// default.
+	MaxFailures int
+
+	// EjectionTime is how long an ejected instance is not picked, 30 seconds
+	// by default. When all instances are ejected, they are picked anyway.
+	EjectionTime time.Duration
+
+	// MaxAttempts is the maximum number of instances a request is sent to,
+	// failing over to the next instance on transport errors, 3 by default.
+	MaxAttempts int
+}
+
+func (c BalancerConfig) withDefaults() BalancerConfig {
+	if c.Strategy == nil {
+		c.Strategy = RoundRobin()
+	}
+
+	if c.MaxFailures <= 0 {
+		c.MaxFailures = 5
+	}
+
+	if c.EjectionTime <= 0 {
+		c.EjectionTime = 30 * time.Second
+	}
+
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+
+	return c
+}
+
+// Host is an instance of a service tracked by the balancer.
+type Host struct {
+	Endpoint
+
+	inFlight int64
+
+	// failures and ejectedUntil are guarded by the mutex of the balancer.
+	failures     int
+	ejectedUntil time.Time
+}
+
+// InFlight returns the number of requests in flight to the host.
+func (h *Host) InFlight() int64 {
+	return atomic.LoadInt64(&h.inFlight)
+}
+
+// Strategy picks the host of a request among the healthy hosts. Strategies
+// must be safe for concurrent use.
+type Strategy interface {
+	// Pick picks one of the hosts, which is never empty. The key is the
+	// BalanceKey of the request, which may be empty.
+	Pick(hosts []*Host, key string) *Host
+}
+
+// RoundRobin returns a strategy which picks the hosts in turn.
+func RoundRobin() Strategy {
+	return &roundRobin{}
+}
+
+type roundRobin struct {
+	next uint64
+}
+
+func (s *roundRobin) Pick(hosts []*Host, _ string) *Host {
+	return hosts[(atomic.AddUint64(&s.next, 1)-1)%uint64(len(hosts))]
+}
+
+// LeastInFlight returns a strategy which picks the host with the least
+// number of requests in flight, breaking ties randomly.
+func LeastInFlight() Strategy {
+	return leastInFlight{}
+}
+
+type leastInFlight struct{}
+
+func (leastInFlight) Pick(hosts []*Host, _ string) *Host {
+	offset := rand.Intn(len(hosts))
+
+	var picked *Host
+	for i := range hosts {
+		host := hosts[(offset+i)%len(hosts)]
+		if picked == nil || host.InFlight() < picked.InFlight() {
+			picked = host
+		}
+	}
+
+	return picked
+}
+
+// WeightedRandom returns a strategy which picks the hosts randomly, in
+// proportion to their weights. Hosts with a weight less than 1 are weighted
+// as 1.
+func WeightedRandom() Strategy {
+	return weightedRandom{}
+}
+
+type weightedRandom struct{}
+
+func (weightedRandom) Pick(hosts []*Host, _ string) *Host {
+	total := 0
+	for _, host := range hosts {
+		total += weight(host)
+	}
+
+	n := rand.Intn(total)
+	for _, host := range hosts {
+		if n -= weight(host); n < 0 {
+			return host
+		}
+	}
+
+	return hosts[len(hosts)-1]
+}
+
+func weight(host *Host) int {
+	if host.Weight < 1 {
+		return 1
+	}
+
+	return host.Weight
+}
+
+// virtualNodes is the number of points of each host on the hash ring.
+const virtualNodes = 100
+
+// ConsistentHash returns a strategy which picks the host by consistent
+// hashing of the BalanceKey of requests, so that the requests with the same
+// key go to the same host, and only the keys of a host move when it is
+// ejected or removed. Requests without a key are sent to random hosts.
+func ConsistentHash() Strategy {
+	return &consistentHash{}
+}
+
+type consistentHash struct {
+	mu    sync.Mutex
+	ring  []ringPoint
+	hosts []*Host
+}
+
+type ringPoint struct {
+	hash uint32
+	host *Host
+}
+
+func (s *consistentHash) Pick(hosts []*Host, key string) *Host {
+	if key == "" {
+		return hosts[rand.Intn(len(hosts))]
+	}
+
+	ring := s.ringOf(hosts)
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= hash })
+	if i == len(ring) {
+		i = 0
+	}
+
+	return ring[i].host
+}
+
+// ringOf returns the hash ring of the hosts, which is rebuilt only when the
+// hosts change.
+func (s *consistentHash) ringOf(hosts []*Host) []ringPoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sameHosts(s.hosts, hosts) {
+		return s.ring
+	}
+
+	ring := make([]ringPoint, 0, len(hosts)*virtualNodes)
+	for _, host := range hosts {
+		for i := 0; i < virtualNodes; i++ {
+			ring = append(ring, ringPoint{
+				hash: crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + host.URL)),
+				host: host,
+			})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	s.ring, s.hosts = ring, append([]*Host(nil), hosts...)
+	return ring
+}
+
+func sameHosts(a []*Host, b []*Host) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// balancer balances the requests between the resolved hosts, and tracks
+// their health passively from the results of requests.
+type balancer struct {
+	config BalancerConfig
+
+	mu    sync.Mutex
+	hosts map[Endpoint]*Host
+}
+
+func newBalancer(config BalancerConfig) *balancer {
+	return &balancer{config: config.withDefaults(), hosts: make(map[Endpoint]*Host)}
+}
+
+// send sends the request to the host picked for it, by joining its base URL
+// and the endpoint. On transport errors, the request fails over to the other
+// hosts, up to MaxAttempts hosts. Requests with absolute endpoints, including
+// Unix socket endpoints, are sent directly.
+func (b *balancer) send(ctx context.Context, method string, endpoint string, key string, header http.Header, send func(endpoint string) (*http.Response, []byte, error)) (*http.Response, []byte, error) {
+	if isAbsolute(endpoint) {
+		return send(endpoint)
+	}
+
+	hosts, err := b.resolve(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		resp    *http.Response
+		resBody []byte
+		tried   = make(map[*Host]bool)
+	)
+	for attempt := 0; attempt < b.config.MaxAttempts; attempt++ {
+		host := b.pick(hosts, tried, key)
+		if host == nil {
+			break
+		}
+		tried[host] = true
+
+		atomic.AddInt64(&host.inFlight, 1)
+		resp, resBody, err = send(joinURL(host.URL, endpoint))
+		atomic.AddInt64(&host.inFlight, -1)
+
+		if ctx.Err() != nil {
+			return resp, resBody, err
+		}
+		b.report(host, resp, err)
+
+		if err == nil || !failover(method, header, err) {
+			break
+		}
+	}
+
+	return resp, resBody, err
+}
+
+// resolve resolves the endpoints, and returns their hosts. The state of hosts
+// is kept as long as they are resolved with the same weight.
+func (b *balancer) resolve(ctx context.Context) ([]*Host, error) {
+	endpoints, err := b.config.Resolver.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hosts := make([]*Host, len(endpoints))
+	resolved := make(map[Endpoint]*Host, len(endpoints))
+	for i, endpoint := range endpoints {
+		host, ok := b.hosts[endpoint]
+		if !ok {
+			host = &Host{Endpoint: endpoint}
+		}
+
+		hosts[i] = host
+		resolved[endpoint] = host
+	}
+	b.hosts = resolved
+
+	return hosts, nil
+}
+
+// pick picks a host which is not tried yet, preferring the hosts which are
+// not ejected. It returns nil when all hosts are tried.
+func (b *balancer) pick(hosts []*Host, tried map[*Host]bool, key string) *Host {
+	b.mu.Lock()
+	now := time.Now()
+	healthy := make([]*Host, 0, len(hosts))
+	untried := make([]*Host, 0, len(hosts))
+	for _, host := range hosts {
+		if tried[host] {
+			continue
+		}
+
+		untried = append(untried, host)
+		if now.After(host.ejectedUntil) {
+			healthy = append(healthy, host)
+		}
+	}
+	b.mu.Unlock()
+
+	switch {
+	case len(healthy) > 0:
+		return b.config.Strategy.Pick(healthy, key)
+	case len(untried) > 0:
+		return b.config.Strategy.Pick(untried, key)
+	default:
+		return nil
+	}
+}
+
+// report updates the health of the host by the result of a request. The host
+// is ejected after MaxFailures consecutive failures.
+func (b *balancer) report(host *Host, resp *http.Response, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := (err != nil && !errors.Is(err, compress.ErrTooLarge)) || (err == nil && resp.StatusCode >= 500)
+	if !failed {
+		host.failures = 0
+		return
+	}
+
+	host.failures++
+	if host.failures >= b.config.MaxFailures {
+		host.failures = 0
+		host.ejectedUntil = time.Now().Add(b.config.EjectionTime)
+	}
+}
+
+// failover checks that the request failed with err can be sent to another
+// host. Idempotent requests, and requests with an idempotency key, fail over
+// on all transport errors, while the others only fail over when the
+// connection is not established, as the host may have processed them.
+func failover(method string, header http.Header, err error) bool {
+	if errors.Is(err, compress.ErrTooLarge) {
+		return false
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	if header.Get(IdempotencyKeyHeader) != "" {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isAbsolute checks that the endpoint is an absolute URL. The socket paths of
+// Unix socket endpoints may not be valid hosts, so they are not parsed.
+func isAbsolute(endpoint string) bool {
+	if strings.HasPrefix(endpoint, "unix://") {
+		return true
+	}
+
+	u, err := url.Parse(endpoint)
+	return err == nil && u.IsAbs()
+}
+
+// joinURL joins the base URL and the endpoint. Unix socket base URLs are
+// joined the same, as their socket paths are escaped.
+func joinURL(base string, endpoint string) string {
+	if endpoint == "" {
+		return base
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(endpoint, "/")
+}
@@ -0,0 +1,231 @@
+package pantopoda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// balancerServer is an instance of a service, counting its requests.
+type balancerServer struct {
+	*httptest.Server
+	hits   int64
+	status int64
+}
+
+// newBalancerServer starts an instance responding with the status. Status 0
+// drops the connections without responding.
+func newBalancerServer(t *testing.T, status int) *balancerServer {
+	t.Helper()
+
+	s := &balancerServer{status: int64(status)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&s.hits, 1)
+
+		status := int(atomic.LoadInt64(&s.status))
+		if status == 0 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+
+		if r.URL.Path != "/users/1" {
+			status = http.StatusNotFound
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *balancerServer) Hits() int64 {
+	return atomic.LoadInt64(&s.hits)
+}
+
+// deadURL returns the URL of a closed server, refusing the connections.
+func deadURL() string {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	return srv.URL
+}
+
+func TestBalancerRoundRobin(t *testing.T) {
+	a, b := newBalancerServer(t, http.StatusOK), newBalancerServer(t, http.StatusOK)
+	c := NewPantopoda(WithBalancer(BalancerConfig{Resolver: StaticResolver(a.URL, b.URL+"/")}))
+
+	for i := 0; i < 4; i++ {
+		if _, err := c.Get("/users/1", Request{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a.Hits() != 2 || b.Hits() != 2 {
+		t.Errorf("expected the requests to be balanced, got %d and %d", a.Hits(), b.Hits())
+	}
+
+	if _, err := c.Get(a.URL+"/users/1", Request{}); err != nil || a.Hits() != 3 {
+		t.Errorf("expected the absolute endpoint to be sent directly, got %v", err)
+	}
+}
+
+func TestBalancerFailover(t *testing.T) {
+	tests := map[string]struct {
+		method   string
+		status   int
+		header   RequestHeaders
+		failover bool
+	}{
+		"refused get":                 {http.MethodGet, -1, nil, true},
+		"refused post":                {http.MethodPost, -1, nil, true},
+		"dropped get":                 {http.MethodGet, 0, nil, true},
+		"dropped put":                 {http.MethodPut, 0, nil, true},
+		"dropped post":                {http.MethodPost, 0, nil, false},
+		"dropped post with key":       {http.MethodPost, 0, RequestHeaders{IdempotencyKeyHeader: "key"}, true},
+		"server error is not retried": {http.MethodGet, http.StatusBadGateway, nil, false},
+	}
+
+	for name, test := range tests {
+		healthy := newBalancerServer(t, http.StatusOK)
+
+		first := deadURL()
+		if test.status >= 0 {
+			first = newBalancerServer(t, test.status).URL
+		}
+
+		c := NewPantopoda(WithBalancer(BalancerConfig{Resolver: StaticResolver(first, healthy.URL)}))
+		_, err := c.Request(test.method, "/users/1", Request{Headers: test.header})
+
+		if failedOver := healthy.Hits() == 1; failedOver != test.failover {
+			t.Errorf("%s: expected the failover to be %t, got %v", name, test.failover, err)
+		}
+		if test.failover && err != nil {
+			t.Errorf("%s: expected the request to succeed, got %v", name, err)
+		}
+	}
+}
+
+func TestBalancerMaxAttempts(t *testing.T) {
+	servers := []*balancerServer{newBalancerServer(t, 0), newBalancerServer(t, 0), newBalancerServer(t, 0)}
+	c := NewPantopoda(WithBalancer(BalancerConfig{
+		Resolver:    StaticResolver(servers[0].URL, servers[1].URL, servers[2].URL),
+		MaxAttempts: 2,
+	}))
+
+	if _, err := c.Get("/users/1", Request{}); err == nil {
+		t.Error("expected the request to fail when all attempts fail")
+	}
+
+	tried := 0
+	for _, s := range servers {
+		if hits := s.Hits(); hits > 1 {
+			t.Errorf("expected a host to be tried once, got %d hits", hits)
+		} else {
+			tried += int(hits)
+		}
+	}
+	if tried != 2 {
+		t.Errorf("expected the request to be sent to 2 hosts, got %d", tried)
+	}
+}
+
+func TestBalancerEjection(t *testing.T) {
+	failing, healthy := newBalancerServer(t, http.StatusInternalServerError), newBalancerServer(t, http.StatusOK)
+	c := NewPantopoda(WithBalancer(BalancerConfig{
+		Resolver:     StaticResolver(failing.URL, healthy.URL),
+		MaxFailures:  2,
+		EjectionTime: 100 * time.Millisecond,
+	}))
+
+	for i := 0; i < 8; i++ {
+		_, _ = c.Get("/users/1", Request{})
+	}
+	if failing.Hits() != 2 || healthy.Hits() != 6 {
+		t.Errorf("expected the failing host to be ejected after 2 failures, got %d and %d hits", failing.Hits(), healthy.Hits())
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	atomic.StoreInt64(&failing.status, http.StatusOK)
+	for i := 0; i < 4; i++ {
+		_, _ = c.Get("/users/1", Request{})
+	}
+	if failing.Hits() != 4 {
+		t.Errorf("expected the host to be picked again after the ejection time, got %d hits", failing.Hits())
+	}
+}
+
+func TestBalancerAllEjected(t *testing.T) {
+	failing := newBalancerServer(t, http.StatusInternalServerError)
+	c := NewPantopoda(WithBalancer(BalancerConfig{Resolver: StaticResolver(failing.URL), MaxFailures: 1}))
+
+	for i := 0; i < 3; i++ {
+		_, _ = c.Get("/users/1", Request{})
+	}
+	if failing.Hits() != 3 {
+		t.Errorf("expected the ejected host to be picked when all hosts are ejected, got %d hits", failing.Hits())
+	}
+}
+
+func TestBalancerResolver(t *testing.T) {
+	c := NewPantopoda(WithBalancer(BalancerConfig{Resolver: StaticResolver()}))
+	if _, err := c.Get("/users/1", Request{}); !errors.Is(err, ErrNoEndpoints) {
+		t.Errorf("expected ErrNoEndpoints, got %v", err)
+	}
+
+	resolveErr := errors.New("resolver is unavailable")
+	c = NewPantopoda(WithBalancer(BalancerConfig{Resolver: ResolverFunc(func(context.Context) ([]Endpoint, error) {
+		return nil, resolveErr
+	})}))
+	if _, err := c.Get("/users/1", Request{}); !errors.Is(err, resolveErr) {
+		t.Errorf("expected the resolver error, got %v", err)
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	hosts := []*Host{
+		{Endpoint: Endpoint{URL: "http://a", Weight: 1}},
+		{Endpoint: Endpoint{URL: "http://b", Weight: 0}},
+		{Endpoint: Endpoint{URL: "http://c", Weight: 8}},
+	}
+
+	hosts[0].inFlight, hosts[1].inFlight, hosts[2].inFlight = 2, 1, 3
+	for i := 0; i < 10; i++ {
+		if picked := LeastInFlight().Pick(hosts, ""); picked != hosts[1] {
+			t.Fatalf("expected the host with the least requests in flight, got %s", picked.URL)
+		}
+	}
+
+	picks := make(map[*Host]int)
+	strategy := WeightedRandom()
+	for i := 0; i < 1000; i++ {
+		picks[strategy.Pick(hosts, "")]++
+	}
+	if picks[hosts[1]] == 0 || picks[hosts[2]] < 5*picks[hosts[0]] {
+		t.Errorf("expected the hosts to be picked by weight, got %d, %d and %d", picks[hosts[0]], picks[hosts[1]], picks[hosts[2]])
+	}
+
+	strategy = ConsistentHash()
+	picked := strategy.Pick(hosts, "user-1")
+	for i := 0; i < 10; i++ {
+		if strategy.Pick(hosts, "user-1") != picked {
+			t.Fatal("expected the same key to pick the same host")
+		}
+	}
+
+	moved := 0
+	for i := 0; i < 100; i++ {
+		key := "user-" + strconv.Itoa(i)
+		if before := strategy.Pick(hosts, key); before != hosts[1] && strategy.Pick([]*Host{hosts[0], hosts[2]}, key) != before {
+			moved++
+		}
+	}
+	if moved != 0 {
+		t.Errorf("expected only the keys of the removed host to move, %d keys moved", moved)
+	}
+}
@@ -0,0 +1,187 @@
+package pantopoda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBatchConcurrency is the number of requests of a batch sent
+// concurrently, when it is not specified.
+const DefaultBatchConcurrency = 8
+
+// BatchRequest is a request of a batch.
+type BatchRequest struct {
+	Method   string
+	Endpoint string
+	Request  Request
+}
+
+// BatchResult is the result of a request of a batch.
+type BatchResult struct {
+	Response Response
+	Err      error
+}
+
+// BatchOptions configures the execution of a batch.
+type BatchOptions struct {
+	// Concurrency is the maximum number of requests sent concurrently,
+	// DefaultBatchConcurrency by default.
+	Concurrency int
+
+	// FailFast cancels the remaining requests of the batch once a request
+	// fails, instead of collecting the results of all requests.
+	FailFast bool
+
+	// Dedup sends the identical GET requests of the batch once, sharing the
+	// result between them. Requests are identical when their endpoints,
+	// query params, headers, payloads, balance keys and response schemas are
+	// the same.
+	Dedup bool
+}
+
+// Batch sends the requests concurrently using a bounded pool of workers, and
+// returns their results in the order of requests. The requests are canceled
+// when the ctx is done. In fail-fast mode, the first error is returned and
+// the requests which are not completed get the context.Canceled error,
+// otherwise the error is always nil and the results hold the errors.
+func (c *Pantopoda) Batch(ctx context.Context, requests []BatchRequest, options BatchOptions) ([]BatchResult, error) {
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]BatchResult, len(requests))
+	group := &flightGroup{calls: make(map[string]*flight)}
+
+	var (
+		firstErr error
+		errOnce  sync.Once
+		wg       sync.WaitGroup
+	)
+
+	indexes := make(chan int)
+	for i := 0; i < concurrency && i < len(requests); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				result := c.batchRequest(ctx, requests[i], options.Dedup, group)
+				results[i] = result
+
+				if result.Err != nil && options.FailFast {
+					errOnce.Do(func() {
+						firstErr = result.Err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	for i := range requests {
+		if ctx.Err() != nil {
+			results[i] = BatchResult{Err: ctx.Err()}
+			continue
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results, firstErr
+}
+
+// batchRequest sends the request of the batch, sharing the result of
+// identical GET requests in flight when dedup is true.
+func (c *Pantopoda) batchRequest(ctx context.Context, request BatchRequest, dedup bool, group *flightGroup) BatchResult {
+	send := func() BatchResult {
+		response, err := c.RequestWithContext(ctx, request.Method, request.Endpoint, request.Request)
+		return BatchResult{Response: response, Err: err}
+	}
+
+	if !dedup || !strings.EqualFold(request.Method, http.MethodGet) {
+		return send()
+	}
+
+	return group.do(requestKey(request), send)
+}
+
+// requestKey identifies the request by its method, endpoint, query params,
+// headers, payload, balance key and response schema, so that only the
+// requests resulting in the same response share it.
+func requestKey(request BatchRequest) string {
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(request.Method) + " " + strconv.Quote(request.Endpoint))
+
+	keys := make([]string, 0, len(request.Request.Query))
+	for key := range request.Request.Query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "\nquery %q=%q", key, request.Request.Query[key])
+	}
+
+	keys = keys[:0]
+	for key := range request.Request.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "\nheader %q=%q", http.CanonicalHeaderKey(key), request.Request.Headers[key])
+	}
+
+	if request.Request.HasBody() {
+		fmt.Fprintf(&b, "\npayload %q", request.Request.Payload.ToJSON())
+	}
+
+	fmt.Fprintf(&b, "\nbalance %q\nschema %p", request.Request.BalanceKey, request.Request.ResponseSchema)
+
+	return b.String()
+}
+
+// flight is a request in flight whose result is shared.
+type flight struct {
+	wg     sync.WaitGroup
+	result BatchResult
+}
+
+// flightGroup shares the results of identical requests in flight.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// do calls fn once for the requests with the same key in flight, and
+// returns its result to all of them.
+func (g *flightGroup) do(key string, fn func() BatchResult) BatchResult {
+	g.mu.Lock()
+	if f, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		f.wg.Wait()
+		return f.result
+	}
+
+	f := new(flight)
+	f.wg.Add(1)
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	f.result = fn()
+	f.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+
+	return f.result
+}
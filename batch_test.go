@@ -0,0 +1,146 @@
+package pantopoda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newBatchServer starts a server which responds the request path after the
+// delay, fails the `/fail` requests and counts the requests along with the
+// maximum number of requests in flight.
+func newBatchServer(t *testing.T, delay time.Duration) (*httptest.Server, *int64, *int64) {
+	t.Helper()
+
+	var hits, inFlight, maxInFlight int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		current := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+
+		for {
+			max := atomic.LoadInt64(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt64(&maxInFlight, max, current) {
+				break
+			}
+		}
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		fmt.Fprintf(w, "%q", r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &hits, &maxInFlight
+}
+
+func TestBatch(t *testing.T) {
+	srv, hits, maxInFlight := newBatchServer(t, 20*time.Millisecond)
+
+	requests := make([]BatchRequest, 6)
+	for i := range requests {
+		requests[i] = BatchRequest{Method: "GET", Endpoint: fmt.Sprintf("%s/%d", srv.URL, i)}
+	}
+
+	results, err := NewPantopoda().Batch(context.Background(), requests, BatchOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatalf("request %d: %v", i, result.Err)
+		}
+		if got, want := result.Response.ToString(), fmt.Sprintf(`"/%d"`, i); got != want {
+			t.Errorf("expected the result %d to be %s, got %s", i, want, got)
+		}
+	}
+
+	if got := atomic.LoadInt64(hits); got != 6 {
+		t.Errorf("expected 6 requests, got %d", got)
+	}
+	if got := atomic.LoadInt64(maxInFlight); got > 2 {
+		t.Errorf("expected at most 2 requests in flight, got %d", got)
+	}
+}
+
+func TestBatchFailFast(t *testing.T) {
+	srv, _, _ := newBatchServer(t, 300*time.Millisecond)
+
+	requests := []BatchRequest{
+		{Method: "GET", Endpoint: srv.URL + "/fail"},
+		{Method: "GET", Endpoint: srv.URL + "/slow"},
+		{Method: "GET", Endpoint: srv.URL + "/queued"},
+	}
+
+	start := time.Now()
+	results, err := NewPantopoda().Batch(context.Background(), requests, BatchOptions{Concurrency: 2, FailFast: true})
+
+	var responseError ResponseError
+	if !errors.As(err, &responseError) {
+		t.Fatalf("expected the error of the failed request, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("expected the remaining requests to be canceled, took %s", elapsed)
+	}
+
+	for i, result := range results[1:] {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("expected the request %d to be canceled, got %v", i+1, result.Err)
+		}
+	}
+
+	results, err = NewPantopoda().Batch(context.Background(), requests[:1], BatchOptions{})
+	if err != nil || results[0].Err == nil {
+		t.Errorf("expected the error in the result without fail-fast, got %v and %v", err, results[0].Err)
+	}
+}
+
+func TestBatchDedup(t *testing.T) {
+	srv, hits, _ := newBatchServer(t, 50*time.Millisecond)
+
+	endpoint := srv.URL + "/users"
+	requests := []BatchRequest{
+		{Method: "GET", Endpoint: endpoint, Request: Request{Query: QueryParams{"page": {"1"}}}},
+		{Method: "get", Endpoint: endpoint, Request: Request{Query: QueryParams{"page": {"1"}}}},
+		{Method: "GET", Endpoint: endpoint, Request: Request{Query: QueryParams{"page": {"2"}}}},
+		{Method: "GET", Endpoint: endpoint, Request: Request{Headers: RequestHeaders{"Authorization": "a"}}},
+		{Method: "GET", Endpoint: endpoint, Request: Request{Headers: RequestHeaders{"authorization": "a"}}},
+		{Method: "GET", Endpoint: endpoint, Request: Request{Headers: RequestHeaders{"Authorization": "b"}}},
+		{Method: "GET", Endpoint: endpoint, Request: Request{BalanceKey: "a"}},
+		{Method: "GET", Endpoint: endpoint, Request: Request{BalanceKey: "b"}},
+		{Method: "GET", Endpoint: endpoint, Request: Request{Payload: JSONBody{"a": 1}}},
+		{Method: "GET", Endpoint: endpoint, Request: Request{Payload: JSONBody{"a": 2}}},
+		{Method: "POST", Endpoint: endpoint},
+		{Method: "POST", Endpoint: endpoint},
+	}
+
+	results, err := NewPantopoda().Batch(context.Background(), requests, BatchOptions{Concurrency: len(requests), Dedup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, result := range results {
+		if result.Err != nil || result.Response.ToString() != `"/users"` {
+			t.Errorf("unexpected result %d: %s, %v", i, result.Response.ToString(), result.Err)
+		}
+	}
+
+	if got := atomic.LoadInt64(hits); got != 10 {
+		t.Errorf("expected 10 requests, got %d", got)
+	}
+}
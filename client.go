@@ -2,33 +2,105 @@ package pantopoda
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Kamva/pantopoda/compress"
 )
 
 // ResponseError is an error implementation for client and server errors in API calls.
 type ResponseError struct {
 	Status  string
 	Payload []byte
+
+	// Problem contains the parsed problem details when the response is an
+	// RFC 7807 problem details document.
+	Problem *ProblemDetails
 }
 
 func (e ResponseError) Error() string {
+	if e.Problem != nil {
+		return fmt.Sprintf("%s: %s: %s", e.Status, e.Problem.Title, e.Problem.Detail)
+	}
+
 	return fmt.Sprintf("%s: %s", e.Status, e.Payload)
 }
 
 // Pantopoda is a HTTP client that makes it easy to send HTTP requests and
 // trivial to integrate with web services.
 type Pantopoda struct {
+	once               sync.Once
+	client             *http.Client
+	transport          *http.Transport
+	pins               map[string]map[string]bool
+	proxyRules         []proxyRule
+	dialer             *dialer
+	validate           bool
+	requestEncoding    string
+	maxResponseSize    int64
+	idempotencyRetries int
+	hedger             *hedger
+	balancer           *balancer
 }
 
+// DefaultMaxResponseSize is the default limit of decoded response bodies.
+const DefaultMaxResponseSize = 64 << 20
+
 // NewPantopoda generate new instance of pantopoda client
-func NewPantopoda() *Pantopoda {
-	return &Pantopoda{}
+func NewPantopoda(options ...Option) *Pantopoda {
+	c := &Pantopoda{maxResponseSize: DefaultMaxResponseSize}
+	c.init()
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// init creates the HTTP client of the client, unless it is created already,
+// so that the zero value of Pantopoda is ready to use too.
+func (c *Pantopoda) init() {
+	c.once.Do(func() {
+		if c.client != nil {
+			return
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		c.client = &http.Client{Transport: transport, CheckRedirect: checkRedirect}
+		c.transport = transport
+		c.dialer = newDialer()
+		transport.Proxy = c.proxy
+		transport.DialContext = c.dialer.dialContext
+	})
+}
+
+// httpClient returns the HTTP client of the client.
+func (c *Pantopoda) httpClient() *http.Client {
+	c.init()
+
+	return c.client
 }
 
 // Request sends a `method` request to the `endpoint` with given request data.
+// Services listening on Unix sockets are requested by the endpoints with the
+// base URL returned by UnixSocket, e.g. `unix://%2Fvar%2Frun%2Fapi.sock/users`.
 func (c *Pantopoda) Request(method string, endpoint string, request Request) (Response, error) {
+	return c.RequestWithContext(context.Background(), method, endpoint, request)
+}
+
+// RequestWithContext sends a `method` request to the `endpoint` with given
+// request data. The request is canceled when the ctx is done.
+func (c *Pantopoda) RequestWithContext(ctx context.Context, method string, endpoint string, request Request) (Response, error) {
+	if c.validate {
+		if err := validatePayload(request.Payload); err != nil {
+			return Response{}, err
+		}
+	}
+
 	var b []byte
 	if request.HasBody() {
 		b = request.Payload.ToJSON()
@@ -39,24 +111,45 @@ func (c *Pantopoda) Request(method string, endpoint string, request Request) (Re
 	if !request.Query.Empty() {
 		endpoint = endpoint + "?" + request.Query.ToString()
 	}
-	req, err := http.NewRequest(method, endpoint, bytes.NewBuffer(b))
-	if err != nil {
-		return Response{}, err
-	}
 
+	header := make(http.Header)
 	for key, value := range request.Headers {
-		req.Header.Set(key, value)
+		header.Set(key, value)
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return Response{}, err
+	if c.requestEncoding != "" && request.HasBody() {
+		buf := new(bytes.Buffer)
+		if err := compress.Compress(buf, c.requestEncoding, b); err != nil {
+			return Response{}, err
+		}
+		b = buf.Bytes()
+		header.Set("Content-Encoding", c.requestEncoding)
+	}
+	if header.Get("Accept-Encoding") == "" {
+		header.Set("Accept-Encoding", strings.Join(compress.Encodings(), ", "))
 	}
 
-	defer resp.Body.Close()
+	retries := 0
+	if c.idempotencyRetries > 0 && (method == http.MethodPost || method == http.MethodPatch) {
+		if header.Get(IdempotencyKeyHeader) == "" {
+			key, err := newIdempotencyKey()
+			if err != nil {
+				return Response{}, err
+			}
+			header.Set(IdempotencyKeyHeader, key)
+		}
+		retries = c.idempotencyRetries
+	}
+
+	resp, resBody, err := c.do(ctx, method, endpoint, request.BalanceKey, b, header)
+	for attempt := 0; attempt < retries && shouldRetry(resp, err); attempt++ {
+		if err := wait(ctx, retryDelay(resp, attempt)); err != nil {
+			return Response{}, err
+		}
+
+		resp, resBody, err = c.do(ctx, method, endpoint, request.BalanceKey, b, header)
+	}
 
-	resBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return Response{}, err
 	}
@@ -66,10 +159,83 @@ func (c *Pantopoda) Request(method string, endpoint string, request Request) (Re
 			Status:  resp.Status,
 			Payload: resBody,
 		}
-		return newResponse(resp, resBody), statusErr
+		if isProblem(resp.Header) {
+			if problem, err := ParseProblem(resBody); err == nil {
+				statusErr.Problem = &problem
+			}
+		}
+		return c.newResponse(resp, resBody), statusErr
+	}
+
+	if request.ResponseSchema != nil {
+		if validationError := ValidateSchema(request.ResponseSchema, resBody); validationError.Failed() {
+			return c.newResponse(resp, resBody), PayloadValidationError{ValidationError: validationError}
+		}
+	}
+
+	return c.newResponse(resp, resBody), nil
+}
+
+// do sends the request to the host picked by the balancer, if it is
+// enabled, hedging it if it is enabled and the request is hedgeable.
+func (c *Pantopoda) do(ctx context.Context, method string, endpoint string, key string, body []byte, header http.Header) (*http.Response, []byte, error) {
+	send := func(endpoint string) (*http.Response, []byte, error) {
+		if c.hedger != nil && hedgeable(method) {
+			return c.hedger.send(ctx, c, method, endpoint, body, header)
+		}
+
+		return c.send(ctx, method, endpoint, body, header)
+	}
+
+	if c.balancer != nil {
+		return c.balancer.send(ctx, method, endpoint, key, header, send)
+	}
+
+	return send(endpoint)
+}
+
+// send sends the request and returns the response along with its decoded
+// body.
+func (c *Pantopoda) send(ctx context.Context, method string, endpoint string, body []byte, header http.Header) (*http.Response, []byte, error) {
+	endpoint, socket, err := unixEndpoint(endpoint)
+	if err != nil {
+		return nil, nil, err
 	}
+	if socket != nil {
+		ctx = withUnixSocket(ctx, *socket)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header = header.Clone()
+	if socket != nil {
+		req.Host = "localhost"
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer resp.Body.Close()
+
+	resBody, err := compress.Decode(resp.Body, resp.Header.Get("Content-Encoding"), c.maxResponseSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+
+	return resp, resBody, nil
+}
+
+func (c *Pantopoda) newResponse(resp *http.Response, body []byte) Response {
+	response := newResponse(resp, body)
+	response.validate = c.validate
 
-	return newResponse(resp, resBody), nil
+	return response
 }
 
 // Get sends a GET request to `endpoint` with given data.
@@ -0,0 +1,93 @@
+package pantopoda
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Kamva/pantopoda/compress"
+)
+
+func TestZeroValueClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	clients := map[string]*Pantopoda{
+		"zero value":            {},
+		"new":                   new(Pantopoda),
+		"session of zero value": new(Pantopoda).Session(),
+	}
+
+	for name, c := range clients {
+		response, err := c.Get(srv.URL, Request{})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := response.ToString(); got != `{"ok":true}` {
+			t.Errorf("%s: unexpected response %s", name, got)
+		}
+	}
+
+	if jar := new(Pantopoda).Jar(); jar != nil {
+		t.Errorf("expected no cookie jar, got %v", jar)
+	}
+}
+
+func TestClientCompression(t *testing.T) {
+	body := `{"name":"` + strings.Repeat("a", 1024) + `"}`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := compress.Decode(r.Body, r.Header.Get("Content-Encoding"), 0)
+		if err != nil || string(payload) != `{"name":"alice"}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		encoding := r.Header.Get("X-Encoding")
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), encoding) {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+
+		buf := new(bytes.Buffer)
+		if err = compress.Compress(buf, encoding, []byte(body)); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Encoding", encoding)
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	for _, encoding := range []string{compress.Gzip, compress.Deflate, compress.Brotli, compress.Zstd} {
+		c := NewPantopoda(WithRequestCompression(encoding))
+		response, err := c.Post(srv.URL, Request{
+			Payload: JSONBody{"name": "alice"},
+			Headers: RequestHeaders{"X-Encoding": encoding},
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		if response.ToString() != body {
+			t.Errorf("%s: expected the response to be decoded, got %d bytes", encoding, len(response.ToString()))
+		}
+		if got := response.Headers.Get("Content-Encoding"); got != "" {
+			t.Errorf("%s: expected the Content-Encoding header to be removed, got %s", encoding, got)
+		}
+	}
+
+	c := NewPantopoda(WithRequestCompression(compress.Gzip), WithMaxResponseSize(int64(len(body)-1)))
+	_, err := c.Post(srv.URL, Request{
+		Payload: JSONBody{"name": "alice"},
+		Headers: RequestHeaders{"X-Encoding": compress.Gzip},
+	})
+	if !errors.Is(err, compress.ErrTooLarge) {
+		t.Errorf("expected the response exceeding the limit to fail with ErrTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,694 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// methods are the HTTP methods of OpenAPI path items, in generation order.
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// pathParam matches the params of OpenAPI paths, such as `{id}`.
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// initialisms are written in upper case in Go names.
+var initialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "API": true, "HTTP": true, "JSON": true,
+	"UUID": true, "IP": true, "SQL": true, "XML": true, "HTML": true,
+}
+
+// generatedNames are the names used by the generated methods and helpers,
+// which the arguments of methods must not shadow.
+var generatedNames = map[string]bool{
+	"c": true, "params": true, "body": true, "request": true, "query": true, "response": true, "result": true, "err": true,
+	"fmt": true, "url": true, "reflect": true, "pantopoda": true, "pathParam": true, "addQuery": true, "addHeader": true,
+	"encodeQuery": true,
+}
+
+// headerTypes are the exported names declared by the header of the generated
+// package, which the types of schemas must not clash with.
+var headerTypes = map[string]bool{"Client": true, "NewClient": true, "Error": true}
+
+// generator generates the Go client package of an OpenAPI document.
+type generator struct {
+	doc     *Document
+	pkg     string
+	types   map[string]string
+	methods strings.Builder
+}
+
+func newGenerator(doc *Document, pkg string) *generator {
+	return &generator{doc: doc, pkg: pkg, types: make(map[string]string)}
+}
+
+// generate returns the formatted source of the client package.
+func (g *generator) generate() ([]byte, error) {
+	for _, name := range sortedKeys(g.doc.Components.Schemas) {
+		g.define(typeName(name), g.doc.Components.Schemas[name])
+	}
+
+	for _, path := range sortedKeys(g.doc.Paths) {
+		item := g.doc.Paths[path]
+
+		var shared []*Parameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, fmt.Errorf("invalid parameters of %s: %s", path, err)
+			}
+		}
+
+		for _, method := range methods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+
+			operation := new(Operation)
+			if err := json.Unmarshal(raw, operation); err != nil {
+				return nil, fmt.Errorf("invalid operation %s %s: %s", method, path, err)
+			}
+
+			operation.Parameters = append(append([]*Parameter{}, shared...), operation.Parameters...)
+			if err := g.operation(strings.ToUpper(method), path, operation); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var src strings.Builder
+	fmt.Fprintf(&src, header, g.doc.Info.Title, g.pkg, g.doc.Info.Title)
+	for _, name := range sortedKeys(g.types) {
+		src.WriteString(g.types[name])
+	}
+	src.WriteString(g.methods.String())
+
+	return format.Source([]byte(src.String()))
+}
+
+// operation generates the client method of the operation. The params of the
+// path must be declared by the operation or its path item.
+func (g *generator) operation(method string, path string, operation *Operation) error {
+	name := goName(operation.OperationID)
+	if name == "" {
+		name = goName(strings.ToLower(method) + " " + pathParam.ReplaceAllString(path, "by $1"))
+	}
+
+	args := make([]string, 0)
+	argNames := make(map[string]bool)
+	pathArgs := make(map[string]string)
+	queryFields := make([]*Parameter, 0)
+
+	for _, param := range operation.Parameters {
+		param = g.parameter(param)
+		if param == nil {
+			continue
+		}
+
+		switch param.In {
+		case "path":
+			arg := argName(param.Name, argNames)
+			pathArgs[param.Name] = arg
+			args = append(args, arg+" "+g.goType(param.Schema, name+goName(param.Name)))
+		case "query", "header":
+			queryFields = append(queryFields, param)
+		}
+	}
+
+	if len(queryFields) > 0 {
+		g.defineParams(name+"Params", queryFields)
+		args = append(args, "params "+name+"Params")
+	}
+
+	bodySchema := operation.RequestBody.JSONSchema()
+	if bodySchema != nil {
+		args = append(args, "body "+g.goType(bodySchema, name+"Body"))
+	}
+
+	result := ""
+	errorModels := make([]string, 0)
+	for _, status := range sortedKeys(operation.Responses) {
+		schema := operation.Responses[status].JSONSchema()
+		if schema == nil {
+			continue
+		}
+
+		if strings.HasPrefix(status, "2") {
+			if result == "" {
+				result = g.goType(schema, name+"Response")
+			}
+			continue
+		}
+
+		suffix := status
+		if status == "default" {
+			suffix = "Default"
+		}
+
+		model := g.goType(schema, name+suffix+"Error")
+		errorModels = append(errorModels, fmt.Sprintf("%q: func() interface{} { return new(%s) },", status, model))
+	}
+
+	endpoint := make([]string, 0)
+	for _, part := range splitPath(path) {
+		if strings.HasPrefix(part, "{") {
+			arg, ok := pathArgs[strings.Trim(part, "{}")]
+			if !ok {
+				return fmt.Errorf("undeclared path param %s of %s %s", part, method, path)
+			}
+			endpoint = append(endpoint, "pathParam("+arg+")")
+		} else {
+			endpoint = append(endpoint, strconv.Quote(part))
+		}
+	}
+
+	w := &g.methods
+	summary := operation.Summary
+	if summary == "" {
+		summary = "sends " + method + " " + path + " request."
+	}
+	fmt.Fprintf(w, "\n// %s %s\n", name, lowerFirst(summary))
+
+	if result == "" {
+		fmt.Fprintf(w, "func (c *Client) %s(%s) error {\n", name, strings.Join(args, ", "))
+	} else {
+		fmt.Fprintf(w, "func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), result)
+		fmt.Fprintf(w, "var result %s\n", result)
+	}
+
+	w.WriteString("request := c.request()\n")
+	for _, param := range queryFields {
+		if param.In == "query" {
+			w.WriteString("query := url.Values{}\n")
+			endpoint = append(endpoint, "encodeQuery(query)")
+			break
+		}
+	}
+	for _, param := range queryFields {
+		if param.In == "query" {
+			fmt.Fprintf(w, "addQuery(query, %q, params.%s)\n", param.Name, goName(param.Name))
+		} else {
+			fmt.Fprintf(w, "addHeader(request.Headers, %q, params.%s)\n", param.Name, goName(param.Name))
+		}
+	}
+	if bodySchema != nil {
+		w.WriteString("request.Payload = pantopoda.StructBody{Value: body}\n")
+	}
+
+	fmt.Fprintf(w, "response, err := c.Client.Request(%q, c.BaseURL+%s, request)\n", method, strings.Join(endpoint, "+"))
+	fmt.Fprintf(w, "if err != nil {\nreturn %sc.error(response, err, map[string]func() interface{}{\n%s\n})\n}\n",
+		map[bool]string{true: "", false: "result, "}[result == ""], strings.Join(errorModels, "\n"))
+
+	if result == "" {
+		w.WriteString("return nil\n}\n")
+	} else {
+		w.WriteString("err = response.Unmarshal(&result)\nreturn result, err\n}\n")
+	}
+
+	return nil
+}
+
+// parameter resolves the parameter reference.
+func (g *generator) parameter(param *Parameter) *Parameter {
+	if param.Ref == "" {
+		return param
+	}
+
+	return g.doc.Components.Parameters[strings.TrimPrefix(param.Ref, "#/components/parameters/")]
+}
+
+// defineParams defines the struct type of query and header params.
+func (g *generator) defineParams(name string, params []*Parameter) {
+	var def strings.Builder
+	fmt.Fprintf(&def, "\n// %s contains the query and header params of %s.\ntype %s struct {\n", name, strings.TrimSuffix(name, "Params"), name)
+
+	for _, param := range params {
+		fieldType := g.goType(param.Schema, name+goName(param.Name))
+		fmt.Fprintf(&def, "%s %s `%s:%q%s`\n", goName(param.Name), fieldType, param.In, param.Name, validateTag(g.resolve(param.Schema), param.Required))
+	}
+	def.WriteString("}\n")
+
+	g.types[name] = def.String()
+}
+
+// define defines the named type of the schema.
+func (g *generator) define(name string, schema *Schema) {
+	if _, ok := g.types[name]; ok {
+		return
+	}
+	g.types[name] = ""
+
+	schema = g.merge(schema)
+
+	var def strings.Builder
+	fmt.Fprintf(&def, "\n// %s is generated from the OpenAPI schema.\n", name)
+
+	if schema.Type != "object" && len(schema.Properties) == 0 {
+		fmt.Fprintf(&def, "type %s %s\n", name, g.goType(schema, name+"Item"))
+		g.types[name] = def.String()
+		return
+	}
+
+	fmt.Fprintf(&def, "type %s struct {\n", name)
+
+	required := make(map[string]bool)
+	for _, property := range schema.Required {
+		required[property] = true
+	}
+
+	for _, property := range sortedKeys(schema.Properties) {
+		propertySchema := schema.Properties[property]
+		fieldType := g.goType(propertySchema, name+goName(property))
+
+		omitEmpty := ",omitempty"
+		if required[property] {
+			omitEmpty = ""
+		}
+
+		fmt.Fprintf(&def, "%s %s `json:\"%s%s\"%s`\n", goName(property), fieldType, property, omitEmpty,
+			validateTag(g.resolve(propertySchema), required[property]))
+	}
+	def.WriteString("}\n")
+
+	g.types[name] = def.String()
+}
+
+// goType returns the Go type of the schema. Inline object schemas are defined
+// as named types, using the given name.
+func (g *generator) goType(schema *Schema, name string) string {
+	if schema == nil {
+		return "interface{}"
+	}
+
+	if schema.Ref != "" {
+		return typeName(refName(schema.Ref))
+	}
+
+	if len(schema.Properties) > 0 || len(schema.AllOf) > 0 {
+		g.define(name, schema)
+		return name
+	}
+
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "integer":
+		if schema.Format == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if schema.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(schema.Items, name+"Item")
+	case "object":
+		if schema.AdditionalProperties != nil {
+			additional := new(Schema)
+			if err := json.Unmarshal(*schema.AdditionalProperties, additional); err == nil {
+				return "map[string]" + g.goType(additional, name+"Value")
+			}
+		}
+		return "map[string]interface{}"
+	default:
+		return "interface{}"
+	}
+}
+
+// resolve returns the component schema if the schema is a reference.
+func (g *generator) resolve(schema *Schema) *Schema {
+	if schema != nil && schema.Ref != "" {
+		if resolved, ok := g.doc.Components.Schemas[refName(schema.Ref)]; ok {
+			return resolved
+		}
+	}
+
+	return schema
+}
+
+// merge combines the properties of the `allOf` schemas into the schema.
+func (g *generator) merge(schema *Schema) *Schema {
+	if len(schema.AllOf) == 0 {
+		return schema
+	}
+
+	merged := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, part := range append([]*Schema{schema}, schema.AllOf...) {
+		part = g.resolve(part)
+		if part != schema {
+			part = g.merge(part)
+		}
+
+		for name, property := range part.Properties {
+			merged.Properties[name] = property
+		}
+		merged.Required = append(merged.Required, part.Required...)
+	}
+
+	return merged
+}
+
+// validateTag returns the `validate` tag for the schema constraints.
+func validateTag(schema *Schema, required bool) string {
+	if schema == nil {
+		return ""
+	}
+
+	rules := make([]string, 0)
+	length := func(min *int, max *int) {
+		if min != nil {
+			rules = append(rules, fmt.Sprintf("min=%d", *min))
+		}
+		if max != nil {
+			rules = append(rules, fmt.Sprintf("max=%d", *max))
+		}
+	}
+
+	switch schema.Type {
+	case "string":
+		length(schema.MinLength, schema.MaxLength)
+		switch schema.Format {
+		case "email", "uuid":
+			rules = append(rules, schema.Format)
+		case "uri":
+			rules = append(rules, "url")
+		}
+	case "array":
+		length(schema.MinItems, schema.MaxItems)
+	case "integer", "number":
+		rules = append(rules, bound("gte", "gt", schema.Minimum, schema.ExclusiveMinimum)...)
+		rules = append(rules, bound("lte", "lt", schema.Maximum, schema.ExclusiveMaximum)...)
+	}
+
+	if len(schema.Enum) > 0 {
+		values := make([]string, 0, len(schema.Enum))
+		for _, value := range schema.Enum {
+			if v := fmt.Sprint(value); !strings.ContainsAny(v, " ,") {
+				values = append(values, v)
+			}
+		}
+
+		if len(values) == len(schema.Enum) {
+			rules = append(rules, "oneof="+strings.Join(values, " "))
+		}
+	}
+
+	if schema.Type == "array" && schema.Items != nil && (schema.Items.Ref != "" || len(schema.Items.Properties) > 0) {
+		rules = append(rules, "dive")
+	}
+
+	switch {
+	case required:
+		rules = append([]string{"required"}, rules...)
+	case len(rules) > 0:
+		rules = append([]string{"omitempty"}, rules...)
+	default:
+		return ""
+	}
+
+	return fmt.Sprintf(" validate:%q", strings.Join(rules, ","))
+}
+
+// bound returns the rule of the inclusive or exclusive bound of numbers,
+// given in either OpenAPI 3.0 or 3.1 form.
+func bound(inclusive string, exclusive string, value *float64, exclusiveValue interface{}) []string {
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	switch e := exclusiveValue.(type) {
+	case float64:
+		return []string{exclusive + "=" + format(e)}
+	case bool:
+		if e && value != nil {
+			return []string{exclusive + "=" + format(*value)}
+		}
+	}
+
+	if value != nil {
+		return []string{inclusive + "=" + format(*value)}
+	}
+
+	return nil
+}
+
+// refName returns the component name of the schema reference.
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// typeName returns the name of the type of the component schema.
+func typeName(name string) string {
+	name = goName(name)
+	if headerTypes[name] {
+		name += "Model"
+	}
+
+	return name
+}
+
+// splitPath splits the path into its literal parts and params.
+func splitPath(path string) []string {
+	parts := make([]string, 0)
+	last := 0
+	for _, match := range pathParam.FindAllStringIndex(path, -1) {
+		if match[0] > last {
+			parts = append(parts, path[last:match[0]])
+		}
+		parts = append(parts, path[match[0]:match[1]])
+		last = match[1]
+	}
+
+	if last < len(path) {
+		parts = append(parts, path[last:])
+	}
+
+	return parts
+}
+
+// goName converts the name into an exported Go identifier.
+func goName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, word := range words {
+		for _, part := range splitCamel(word) {
+			if upper := strings.ToUpper(part); initialisms[upper] {
+				b.WriteString(upper)
+			} else {
+				b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+			}
+		}
+	}
+
+	result := b.String()
+	if result != "" && unicode.IsDigit(rune(result[0])) {
+		result = "N" + result
+	}
+
+	return result
+}
+
+// splitCamel splits the camel case word into its parts.
+func splitCamel(word string) []string {
+	parts := make([]string, 0)
+	start := 0
+	runes := []rune(word)
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsUpper(runes[i]) && !unicode.IsUpper(runes[i-1]) {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+
+	return append(parts, string(runes[start:]))
+}
+
+// argName converts the param name into the name of a method argument, which
+// is not used by the other arguments, and does not clash with keywords,
+// predeclared names and the names of the generated code.
+func argName(name string, used map[string]bool) string {
+	arg := lowerFirst(goName(name))
+	switch {
+	case arg == "":
+		arg = "arg"
+	case token.IsKeyword(arg) || types.Universe.Lookup(arg) != nil:
+		arg += "_"
+	case generatedNames[arg]:
+		arg += "Arg"
+	}
+
+	for base, i := arg, 2; used[arg]; i++ {
+		arg = base + strconv.Itoa(i)
+	}
+	used[arg] = true
+
+	return arg
+}
+
+// lowerFirst converts the first letter of the name into lower case, keeping
+// initialisms in a single case.
+func lowerFirst(name string) string {
+	for initialism := range initialisms {
+		if name == initialism {
+			return strings.ToLower(name)
+		}
+	}
+
+	if name == "" {
+		return name
+	}
+
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// sortedKeys returns the keys of the map in sorted order.
+func sortedKeys(m interface{}) []string {
+	keys := make([]string, 0)
+	switch m := m.(type) {
+	case map[string]*Schema:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]map[string]json.RawMessage:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]*Body:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]string:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// header is the header of the generated package, including the client type
+// and the helpers.
+const header = `// Code generated by pantopoda-gen. DO NOT EDIT.
+
+// Package %[2]s is the client of %[1]s API.
+package %[2]s
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+
+	"github.com/Kamva/pantopoda"
+)
+
+// Client is the client of %[3]s API.
+type Client struct {
+	// BaseURL is prepended to the path of operations.
+	BaseURL string
+
+	// Client is the pantopoda client used to send requests.
+	Client *pantopoda.Pantopoda
+
+	// Headers are sent along with every request.
+	Headers pantopoda.RequestHeaders
+}
+
+// NewClient generate new instance of the API client.
+func NewClient(baseURL string, client *pantopoda.Pantopoda) *Client {
+	if client == nil {
+		client = pantopoda.NewPantopoda()
+	}
+
+	return &Client{BaseURL: baseURL, Client: client, Headers: pantopoda.RequestHeaders{}}
+}
+
+// Error is returned when the API responds with an error status.
+type Error struct {
+	pantopoda.ResponseError
+
+	// Model is the decoded response body, when the operation documents a
+	// schema for the response status.
+	Model interface{}
+}
+
+func (c *Client) request() pantopoda.Request {
+	headers := pantopoda.RequestHeaders{"Content-Type": "application/json"}
+	for key, value := range c.Headers {
+		headers[key] = value
+	}
+
+	return pantopoda.Request{Headers: headers}
+}
+
+func (c *Client) error(response pantopoda.Response, err error, models map[string]func() interface{}) error {
+	responseError, ok := err.(pantopoda.ResponseError)
+	if !ok {
+		return err
+	}
+
+	apiError := &Error{ResponseError: responseError}
+
+	model, ok := models[fmt.Sprint(response.StatusCode.Int())]
+	if !ok {
+		model, ok = models["default"]
+	}
+
+	if ok {
+		value := model()
+		if response.Unmarshal(value) == nil {
+			apiError.Model = value
+		}
+	}
+
+	return apiError
+}
+
+func pathParam(value interface{}) string {
+	return url.PathEscape(fmt.Sprint(value))
+}
+
+func addQuery(query url.Values, key string, value interface{}) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || v.IsZero() {
+		return
+	}
+
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			query.Add(key, fmt.Sprint(v.Index(i).Interface()))
+		}
+		return
+	}
+
+	query.Set(key, fmt.Sprint(value))
+}
+
+func encodeQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+
+	return "?" + query.Encode()
+}
+
+func addHeader(headers pantopoda.RequestHeaders, key string, value interface{}) {
+	if v := reflect.ValueOf(value); v.IsValid() && !v.IsZero() {
+		headers[key] = fmt.Sprint(value)
+	}
+}
+`
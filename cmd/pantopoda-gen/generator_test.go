@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+// generateFixture generates the client of the fixture document.
+func generateFixture(t *testing.T, path string) string {
+	t.Helper()
+
+	document, err := loadDocument(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := newGenerator(document, "petstore").generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(src)
+}
+
+func TestGenerate(t *testing.T) {
+	src := generateFixture(t, "testdata/petstore.yaml")
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "client.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err = config.Check("petstore", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("generated client does not compile: %v\n%s", err, src)
+	}
+
+	expected := []string{
+		"type Pet struct {",
+		"ID     int64  `json:\"id,omitempty\"`",
+		"Name   string `json:\"name\" validate:\"required,min=1\"`",
+		"Status string `json:\"status,omitempty\" validate:\"omitempty,oneof=available sold\"`",
+		"Tag    string `json:\"tag,omitempty\" validate:\"omitempty,max=16\"`",
+		"Limit      int32  `query:\"limit\" validate:\"omitempty,lte=100\"`",
+		"XRequestID string `header:\"X-Request-ID\" validate:\"omitempty,uuid\"`",
+		"func (c *Client) ListPets(params ListPetsParams) ([]Pet, error) {",
+		"func (c *Client) CreatePet(body Pet) (Pet, error) {",
+		"func (c *Client) GetPetsByPetID(petID int64) (Pet, error) {",
+		"func (c *Client) DeletePet(petID int64) error {",
+		`c.BaseURL+"/pets/"+pathParam(petID)`,
+		`"default": func() interface{} { return new(ErrorModel) },`,
+	}
+	for _, snippet := range expected {
+		if !strings.Contains(src, snippet) {
+			t.Errorf("expected the generated client to contain %s", snippet)
+		}
+	}
+}
+
+func TestGenerateUndeclaredPathParam(t *testing.T) {
+	document := &Document{Paths: map[string]map[string]json.RawMessage{
+		"/pets/{petId}": {"get": json.RawMessage(`{"operationId":"getPet"}`)},
+	}}
+
+	if _, err := newGenerator(document, "petstore").generate(); err == nil || !strings.Contains(err.Error(), "{petId}") {
+		t.Errorf("expected the undeclared path param to be rejected, got %v", err)
+	}
+}
+
+func TestSchemaType(t *testing.T) {
+	tests := map[string]SchemaType{
+		`"string"`:                    "string",
+		`["string"]`:                  "string",
+		`["integer","null"]`:          "integer",
+		`["null","array"]`:            "array",
+		`["string","integer"]`:        "",
+		`["string","integer","null"]`: "",
+	}
+
+	for raw, want := range tests {
+		var got SchemaType
+		if err := got.UnmarshalJSON([]byte(raw)); err != nil {
+			t.Errorf("%s: %v", raw, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("expected type %s to be %q, got %q", raw, want, got)
+		}
+	}
+
+	var invalid SchemaType
+	if err := invalid.UnmarshalJSON([]byte(`1`)); err == nil {
+		t.Error("expected a numeric type to be rejected")
+	}
+}
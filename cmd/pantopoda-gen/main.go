@@ -0,0 +1,57 @@
+// Command pantopoda-gen generates typed API clients from OpenAPI 3 documents.
+//
+// The generated package contains a struct type for each schema of the
+// document, with `validate` tags derived from the schema constraints so that
+// pantopoda.Validate works on them, and a Client with a method for each
+// operation which sends the request through the Pantopoda client.
+//
+//	pantopoda-gen -spec openapi.yaml -package users -out users/client.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	spec := flag.String("spec", "", "path of the OpenAPI document, in json or yaml")
+	pkg := flag.String("package", "client", "name of the generated package")
+	out := flag.String("out", "", "path of the generated file, stdout if empty")
+	flag.Parse()
+
+	if *spec == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*spec, *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "pantopoda-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(spec string, pkg string, out string) error {
+	document, err := loadDocument(spec)
+	if err != nil {
+		return err
+	}
+
+	src, err := newGenerator(document, pkg).generate()
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(out, src, 0644)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Document is the subset of OpenAPI 3 document used for generating clients.
+type Document struct {
+	Info struct {
+		Title string `json:"title"`
+	} `json:"info"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas    map[string]*Schema    `json:"schemas"`
+		Parameters map[string]*Parameter `json:"parameters"`
+	} `json:"components"`
+}
+
+// Operation is an OpenAPI operation.
+type Operation struct {
+	OperationID string           `json:"operationId"`
+	Summary     string           `json:"summary"`
+	Parameters  []*Parameter     `json:"parameters"`
+	RequestBody *Body            `json:"requestBody"`
+	Responses   map[string]*Body `json:"responses"`
+}
+
+// Parameter is an OpenAPI parameter.
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// Body is an OpenAPI request body or response.
+type Body struct {
+	Description string               `json:"description"`
+	Required    bool                 `json:"required"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// MediaType is an OpenAPI media type object.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of OpenAPI schema object used for generating types.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 SchemaType         `json:"type"`
+	Format               string             `json:"format"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *Schema            `json:"items"`
+	AdditionalProperties *json.RawMessage   `json:"additionalProperties"`
+	AllOf                []*Schema          `json:"allOf"`
+	Enum                 []interface{}      `json:"enum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+
+	// ExclusiveMinimum and ExclusiveMaximum are booleans in OpenAPI 3.0,
+	// making Minimum and Maximum exclusive, and numbers in OpenAPI 3.1.
+	ExclusiveMinimum interface{} `json:"exclusiveMinimum"`
+	ExclusiveMaximum interface{} `json:"exclusiveMaximum"`
+}
+
+// SchemaType is the type of a schema. OpenAPI 3.1 types may be arrays, such
+// as `["string", "null"]`, which are reduced to their only non-null type, and
+// to no type when there are several of them.
+type SchemaType string
+
+// UnmarshalJSON decodes the type from a string or an array of strings.
+func (t *SchemaType) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*t = SchemaType(name)
+		return nil
+	}
+
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+
+	*t = ""
+	for _, name := range names {
+		if name == "null" {
+			continue
+		}
+		if *t != "" {
+			*t = ""
+			return nil
+		}
+		*t = SchemaType(name)
+	}
+
+	return nil
+}
+
+// JSONSchema returns the json schema of the media types, if there is any.
+func (b *Body) JSONSchema() *Schema {
+	if b == nil {
+		return nil
+	}
+
+	for mediaType, content := range b.Content {
+		if strings.Contains(mediaType, "json") {
+			return content.Schema
+		}
+	}
+
+	return nil
+}
+
+// loadDocument reads the OpenAPI document from the file, in json or yaml.
+func loadDocument(path string) (*Document, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(path, ".json") {
+		var value interface{}
+		if err = yaml.Unmarshal(b, &value); err != nil {
+			return nil, err
+		}
+
+		if b, err = json.Marshal(jsonCompatible(value)); err != nil {
+			return nil, err
+		}
+	}
+
+	document := new(Document)
+	if err = json.Unmarshal(b, document); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %s", err)
+	}
+
+	return document, nil
+}
+
+// jsonCompatible converts the maps decoded by yaml into maps with string keys.
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = jsonCompatible(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonCompatible(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,250 @@
+// Package compress implements the content codings of HTTP bodies, used for
+// compressing responses in the api package and for compressing requests and
+// decoding responses in the client.
+//
+// The `gzip`, `deflate`, `br` and `zstd` codings are registered by default.
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings registered by default.
+const (
+	Gzip    = "gzip"
+	Deflate = "deflate"
+	Brotli  = "br"
+	Zstd    = "zstd"
+)
+
+var (
+	// ErrTooLarge is returned when the decoded body exceeds the limit.
+	ErrTooLarge = errors.New("compress: decoded body exceeds the limit")
+
+	// ErrUnsupported is returned for content codings with no registered codec.
+	ErrUnsupported = errors.New("compress: unsupported content coding")
+)
+
+// Codec compresses and decompresses bodies in a content coding.
+type Codec interface {
+	// NewWriter returns a writer compressing into w. The compressed body is
+	// complete after the writer is closed.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+
+	// NewReader returns a reader decompressing r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// registeredCodec is a codec along with the content coding it registered for.
+type registeredCodec struct {
+	encoding string
+	codec    Codec
+}
+
+var codecsMu sync.RWMutex
+
+// codecs is the list of registered codecs in order of preference.
+var codecs = []registeredCodec{
+	{encoding: Brotli, codec: BrotliCodec{}},
+	{encoding: Zstd, codec: ZstdCodec{}},
+	{encoding: Gzip, codec: GzipCodec{}},
+	{encoding: Deflate, codec: DeflateCodec{}},
+}
+
+// Register registers the codec for given content coding. If the coding is
+// already registered, its codec will be replaced.
+func Register(encoding string, codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	encoding = strings.ToLower(encoding)
+	for i, c := range codecs {
+		if c.encoding == encoding {
+			codecs[i].codec = codec
+			return
+		}
+	}
+
+	codecs = append(codecs, registeredCodec{encoding: encoding, codec: codec})
+}
+
+// Get returns the codec of the content coding.
+func Get(encoding string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	for _, c := range codecs {
+		if c.encoding == encoding {
+			return c.codec, true
+		}
+	}
+
+	return nil, false
+}
+
+// Encodings returns the registered content codings in order of preference.
+func Encodings() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	encodings := make([]string, len(codecs))
+	for i, c := range codecs {
+		encodings[i] = c.encoding
+	}
+
+	return encodings
+}
+
+// Compress writes the body compressed with the content coding to w.
+func Compress(w io.Writer, encoding string, body []byte) error {
+	codec, ok := Get(encoding)
+	if !ok {
+		return fmt.Errorf("%w %s", ErrUnsupported, encoding)
+	}
+
+	writer, err := codec.NewWriter(w)
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(body); err != nil {
+		_ = writer.Close()
+		return err
+	}
+
+	return writer.Close()
+}
+
+// Decode decodes the body encoded with the content codings of the
+// Content-Encoding header, which are listed in the order they are applied.
+// Reading more than limit bytes of decoded body fails with ErrTooLarge, a
+// limit less than or equal to zero means no limit.
+func Decode(body io.Reader, contentEncoding string, limit int64) ([]byte, error) {
+	encodings := strings.Split(contentEncoding, ",")
+
+	closers := make([]io.Closer, 0, len(encodings))
+	defer func() {
+		for _, closer := range closers {
+			_ = closer.Close()
+		}
+	}()
+
+	// Empty bodies, such as the body of HEAD responses, are not encoded even
+	// if they have Content-Encoding.
+	buffered := bufio.NewReader(body)
+	if _, err := buffered.Peek(1); err == io.EOF {
+		return []byte{}, nil
+	}
+	body = buffered
+
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+		if encoding == "" || encoding == "identity" {
+			continue
+		}
+
+		codec, ok := Get(encoding)
+		if !ok {
+			return nil, fmt.Errorf("%w %s", ErrUnsupported, encoding)
+		}
+
+		reader, err := codec.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+
+		closers = append(closers, reader)
+		body = reader
+	}
+
+	return readAll(body, limit)
+}
+
+// readAll reads the body, failing with ErrTooLarge if it exceeds the limit.
+func readAll(body io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return ioutil.ReadAll(body)
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(b)) > limit {
+		return nil, ErrTooLarge
+	}
+
+	return b, nil
+}
+
+// GzipCodec implements the `gzip` content coding.
+type GzipCodec struct{}
+
+// NewWriter returns a gzip writer.
+func (GzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+// NewReader returns a gzip reader.
+func (GzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// DeflateCodec implements the `deflate` content coding, which is the zlib
+// format.
+type DeflateCodec struct{}
+
+// NewWriter returns a zlib writer.
+func (DeflateCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriter(w), nil
+}
+
+// NewReader returns a zlib reader.
+func (DeflateCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
+
+// BrotliCodec implements the `br` content coding.
+type BrotliCodec struct{}
+
+// NewWriter returns a brotli writer.
+func (BrotliCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return brotli.NewWriter(w), nil
+}
+
+// NewReader returns a brotli reader.
+func (BrotliCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(brotli.NewReader(r)), nil
+}
+
+// ZstdCodec implements the `zstd` content coding.
+type ZstdCodec struct{}
+
+// NewWriter returns a zstd writer.
+func (ZstdCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+// NewReader returns a zstd reader. The window size is limited to 8MB, as
+// recommended for HTTP by RFC 8878.
+func (ZstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r, zstd.WithDecoderMaxWindow(8<<20), zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+
+	return decoder.IOReadCloser(), nil
+}
@@ -0,0 +1,127 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+var body = []byte(strings.Repeat(`{"id":1,"name":"alice"}`, 100))
+
+// encode compresses the body with the content codings in order.
+func encode(t *testing.T, body []byte, encodings ...string) []byte {
+	t.Helper()
+
+	for _, encoding := range encodings {
+		buf := new(bytes.Buffer)
+		if err := Compress(buf, encoding, body); err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		body = buf.Bytes()
+	}
+
+	return body
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, encoding := range []string{Gzip, Deflate, Brotli, Zstd} {
+		compressed := encode(t, body, encoding)
+		if len(compressed) >= len(body) {
+			t.Errorf("%s: expected the body to be compressed, got %d bytes", encoding, len(compressed))
+		}
+
+		decoded, err := Decode(bytes.NewReader(compressed), " "+strings.ToUpper(encoding), 0)
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		if !bytes.Equal(decoded, body) {
+			t.Errorf("%s: unexpected decoded body %s", encoding, decoded)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := map[string][]byte{
+		"":                      body,
+		"identity":              body,
+		"gzip, br":              encode(t, body, Gzip, Brotli),
+		"zstd,identity,deflate": encode(t, body, Zstd, Deflate),
+	}
+
+	for contentEncoding, encoded := range tests {
+		decoded, err := Decode(bytes.NewReader(encoded), contentEncoding, 0)
+		if err != nil || !bytes.Equal(decoded, body) {
+			t.Errorf("%q: expected the body to be decoded, got %v", contentEncoding, err)
+		}
+	}
+
+	if decoded, err := Decode(bytes.NewReader(nil), Gzip, 0); err != nil || len(decoded) != 0 {
+		t.Errorf("expected the empty body not to be decoded, got %q, %v", decoded, err)
+	}
+	if _, err := Decode(bytes.NewReader(body), Gzip, 0); err == nil {
+		t.Error("expected the invalid gzip body to be rejected")
+	}
+	if _, err := Decode(bytes.NewReader(body), "compress", 0); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected the unsupported coding to be rejected, got %v", err)
+	}
+	if err := Compress(ioutil.Discard, "compress", body); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected the unsupported coding to be rejected, got %v", err)
+	}
+}
+
+func TestDecodeLimit(t *testing.T) {
+	for _, encoding := range []string{Gzip, Deflate, Brotli, Zstd} {
+		compressed := encode(t, body, encoding)
+
+		if _, err := Decode(bytes.NewReader(compressed), encoding, int64(len(body)-1)); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("%s: expected the body exceeding the limit to fail with ErrTooLarge, got %v", encoding, err)
+		}
+		if _, err := Decode(bytes.NewReader(compressed), encoding, int64(len(body))); err != nil {
+			t.Errorf("%s: expected the body at the limit to be decoded, got %v", encoding, err)
+		}
+	}
+
+	if _, err := Decode(bytes.NewReader(body), "", 10); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected the identity body to be limited, got %v", err)
+	}
+}
+
+// upperCodec is a codec for testing, which upper cases the body.
+type upperCodec struct{}
+
+func (upperCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+func (upperCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	b, err := ioutil.ReadAll(r)
+	return ioutil.NopCloser(bytes.NewReader(bytes.ToUpper(b))), err
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	defer func(registered []registeredCodec) { codecs = registered }(append([]registeredCodec(nil), codecs...))
+
+	Register("X-Upper", upperCodec{})
+	Register(Gzip, upperCodec{})
+
+	if got := Encodings(); strings.Join(got, ",") != "br,zstd,gzip,deflate,x-upper" {
+		t.Errorf("unexpected encodings %v", got)
+	}
+
+	for _, encoding := range []string{"x-upper", Gzip} {
+		if decoded, err := Decode(strings.NewReader("alice"), encoding, 0); err != nil || string(decoded) != "ALICE" {
+			t.Errorf("%s: expected the registered codec to be used, got %q, %v", encoding, decoded, err)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package pantopoda
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CookieJar is a cookie jar which follows the rules of RFC 6265 on the
+// domain, path, secure flag and expiry of cookies. A file-persisted jar
+// saves its cookies, including session cookies, to the file whenever they
+// are set, so that the session survives the restarts of the process.
+type CookieJar struct {
+	jar  *cookiejar.Jar
+	psl  cookiejar.PublicSuffixList
+	path string
+
+	mu      sync.Mutex
+	entries []cookieEntry
+	err     error
+}
+
+// cookieEntry is a cookie along with the URL it is set from, which is
+// replayed on the jar when the file is loaded.
+type cookieEntry struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// NewCookieJar generate new in-memory cookie jar. The psl is used to reject
+// cookies set for public suffixes like `co.uk`, e.g. publicsuffix.List of
+// golang.org/x/net/publicsuffix, and may be nil for trusted services.
+func NewCookieJar(psl cookiejar.PublicSuffixList) *CookieJar {
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: psl})
+
+	return &CookieJar{jar: jar, psl: psl}
+}
+
+// NewFileCookieJar generate new cookie jar persisted to the file at path,
+// loading the cookies already saved to it, if it exists.
+func NewFileCookieJar(path string, psl cookiejar.PublicSuffixList) (*CookieJar, error) {
+	j := NewCookieJar(psl)
+	j.path = path
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []cookieEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		u, err := url.Parse(entry.URL)
+		if err != nil || entry.Cookie == nil {
+			continue
+		}
+		j.setCookies(u, []*http.Cookie{entry.Cookie})
+	}
+
+	return j, nil
+}
+
+// SetCookies handles the receipt of the cookies in a reply for the URL.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.setCookies(u, cookies)
+
+	if j.path != "" {
+		j.err = j.save()
+	}
+}
+
+// Cookies returns the cookies to send in a request for the URL.
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Err returns the error of the last save of a file-persisted jar, if it is
+// failed.
+func (j *CookieJar) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.err
+}
+
+// setCookies sets the cookies on the jar, and records them for saving if
+// the jar is file-persisted. The Max-Age of cookies is recorded as their
+// expiry time, so that it is not extended by loading them again.
+func (j *CookieJar) setCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+	if j.path == "" {
+		return
+	}
+
+	now := time.Now()
+	for _, cookie := range cookies {
+		c := *cookie
+		if c.MaxAge > 0 {
+			c.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+			c.MaxAge = 0
+		}
+
+		entry := cookieEntry{URL: (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(), Cookie: &c}
+
+		// The entry replaces the previous entry of the same cookie.
+		replaced := false
+		for i, e := range j.entries {
+			if sameCookie(e, entry) {
+				j.entries[i], replaced = entry, true
+				break
+			}
+		}
+		if !replaced {
+			j.entries = append(j.entries, entry)
+		}
+	}
+}
+
+// sameCookie checks that the entries set the same cookie, i.e. the cookies
+// have the same name, domain and path, and are set from the same host.
+func sameCookie(a cookieEntry, b cookieEntry) bool {
+	return a.Cookie.Name == b.Cookie.Name &&
+		a.Cookie.Domain == b.Cookie.Domain &&
+		a.Cookie.Path == b.Cookie.Path &&
+		hostOf(a.URL) == hostOf(b.URL)
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	return u.Host
+}
+
+// save writes the cookies which are not expired or deleted to the file,
+// replacing it atomically.
+func (j *CookieJar) save() error {
+	now := time.Now()
+	entries := make([]cookieEntry, 0, len(j.entries))
+	for _, entry := range j.entries {
+		expired := entry.Cookie.MaxAge < 0 || (!entry.Cookie.Expires.IsZero() && entry.Cookie.Expires.Before(now))
+		if !expired {
+			entries = append(entries, entry)
+		}
+	}
+	j.entries = entries
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(j.path), filepath.Base(j.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), j.path)
+}
+
+// AddCookie adds the cookie to the Cookie header of the request.
+func (r *Request) AddCookie(cookie *http.Cookie) {
+	req := http.Request{Header: make(http.Header)}
+	req.AddCookie(cookie)
+
+	if r.Headers == nil {
+		r.Headers = make(RequestHeaders)
+	}
+
+	if existing := r.Headers["Cookie"]; existing != "" {
+		r.Headers["Cookie"] = existing + "; " + req.Header.Get("Cookie")
+	} else {
+		r.Headers["Cookie"] = req.Header.Get("Cookie")
+	}
+}
+
+// Cookies parses and returns the cookies set in the Set-Cookie headers of
+// the response.
+func (r Response) Cookies() []*http.Cookie {
+	return (&http.Response{Header: r.Headers}).Cookies()
+}
+
+// Cookie returns the cookie of the response with the given name, or
+// http.ErrNoCookie if it is not set.
+func (r Response) Cookie(name string) (*http.Cookie, error) {
+	for _, cookie := range r.Cookies() {
+		if cookie.Name == name {
+			return cookie, nil
+		}
+	}
+
+	return nil, http.ErrNoCookie
+}
+
+// Jar returns the cookie jar of the client, which is nil when cookies are
+// not enabled.
+func (c *Pantopoda) Jar() http.CookieJar {
+	return c.httpClient().Jar
+}
+
+// Session returns a client which shares the options and connections of the
+// client, but has its own in-memory cookie jar, e.g. to log in to a service
+// as different users.
+func (c *Pantopoda) Session() *Pantopoda {
+	client := c.httpClient()
+
+	var psl cookiejar.PublicSuffixList
+	if jar, ok := client.Jar.(*CookieJar); ok {
+		psl = jar.psl
+	}
+
+	return &Pantopoda{
+		client: &http.Client{
+			Transport:     client.Transport,
+			CheckRedirect: client.CheckRedirect,
+			Jar:           NewCookieJar(psl),
+			Timeout:       client.Timeout,
+		},
+		transport:          c.transport,
+		pins:               c.pins,
+		proxyRules:         c.proxyRules,
+		dialer:             c.dialer,
+		validate:           c.validate,
+		requestEncoding:    c.requestEncoding,
+		maxResponseSize:    c.maxResponseSize,
+		idempotencyRetries: c.idempotencyRetries,
+		hedger:             c.hedger,
+		balancer:           c.balancer,
+	}
+}
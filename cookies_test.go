@@ -0,0 +1,157 @@
+package pantopoda
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+// newSessionServer starts a server which logs in the user of the `user`
+// query param with a session cookie on `/login`, logs out on `/logout`, and
+// responds the session cookie it receives on other paths.
+func newSessionServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: r.URL.Query().Get("user"), Path: "/", MaxAge: 3600})
+		case "/logout":
+			http.SetCookie(w, &http.Cookie{Name: "session", Path: "/", MaxAge: -1})
+		}
+
+		session := ""
+		if cookie, err := r.Cookie("session"); err == nil {
+			session = cookie.Value
+		}
+		w.Write([]byte(`{"session":"` + session + `"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func session(t *testing.T, c *Pantopoda, endpoint string) string {
+	t.Helper()
+
+	response, err := c.Get(endpoint, Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body struct {
+		Session string `json:"session"`
+	}
+	if err := response.Unmarshal(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	return body.Session
+}
+
+func TestWithCookieJar(t *testing.T) {
+	srv := newSessionServer(t)
+	c := NewPantopoda(WithCookieJar(NewCookieJar(nil)))
+
+	if got := session(t, c, srv.URL+"/me"); got != "" {
+		t.Errorf("expected no session before login, got %q", got)
+	}
+
+	session(t, c, srv.URL+"/login?user=alice")
+	if got := session(t, c, srv.URL+"/me"); got != "alice" {
+		t.Errorf("expected session %q, got %q", "alice", got)
+	}
+
+	session(t, c, srv.URL+"/logout")
+	if got := session(t, c, srv.URL+"/me"); got != "" {
+		t.Errorf("expected no session after logout, got %q", got)
+	}
+}
+
+func TestSession(t *testing.T) {
+	srv := newSessionServer(t)
+	c := NewPantopoda(WithCookieJar(NewCookieJar(nil)))
+	alice, bob := c.Session(), c.Session()
+
+	session(t, alice, srv.URL+"/login?user=alice")
+	session(t, bob, srv.URL+"/login?user=bob")
+
+	tests := map[string]*Pantopoda{"": c, "alice": alice, "bob": bob}
+	for want, client := range tests {
+		if got := session(t, client, srv.URL+"/me"); got != want {
+			t.Errorf("expected session %q, got %q", want, got)
+		}
+	}
+}
+
+func TestFileCookieJar(t *testing.T) {
+	srv := newSessionServer(t)
+	path := filepath.Join(t.TempDir(), "cookies.json")
+
+	jar, err := NewFileCookieJar(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session(t, NewPantopoda(WithCookieJar(jar)), srv.URL+"/login?user=alice")
+	if err := jar.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The session survives loading the jar again from the file.
+	jar, err = NewFileCookieJar(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewPantopoda(WithCookieJar(jar))
+	if got := session(t, c, srv.URL+"/me"); got != "alice" {
+		t.Errorf("expected session %q from the file, got %q", "alice", got)
+	}
+
+	// The deleted cookie is removed from the file too.
+	session(t, c, srv.URL+"/logout")
+	jar, err = NewFileCookieJar(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(srv.URL)
+	if cookies := jar.Cookies(u); len(cookies) != 0 {
+		t.Errorf("expected no cookies after logout, got %v", cookies)
+	}
+}
+
+func TestCookieHelpers(t *testing.T) {
+	srv := newSessionServer(t)
+	c := NewPantopoda()
+
+	request := Request{}
+	request.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	request.AddCookie(&http.Cookie{Name: "session", Value: "alice"})
+	if got := request.Headers["Cookie"]; got != "theme=dark; session=alice" {
+		t.Errorf("unexpected Cookie header %q", got)
+	}
+
+	response, err := c.Get(srv.URL+"/me", request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := response.ToString(); got != `{"session":"alice"}` {
+		t.Errorf("expected the added cookie to be sent, got %s", got)
+	}
+
+	response, err = c.Get(srv.URL+"/login?user=bob", Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookie, err := response.Cookie("session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cookie.Value != "bob" || cookie.MaxAge != 3600 {
+		t.Errorf("unexpected session cookie %+v", cookie)
+	}
+	if _, err := response.Cookie("missing"); err != http.ErrNoCookie {
+		t.Errorf("expected http.ErrNoCookie, got %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package pantopoda
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencySamples is the number of recent latencies the hedging delay is
+// learned from, and minLatencySamples is the number required to learn it.
+// minHedgeDelay is the lower bound of the learned delay, as the latencies of
+// fast responses may be recorded as zero.
+const (
+	latencySamples    = 128
+	minLatencySamples = 20
+	minHedgeDelay     = time.Millisecond
+)
+
+// HedgePolicy configures hedged requests. A hedged request is a duplicate of
+// a GET request which is sent when the original request takes longer than
+// the hedging delay. The first successful response is taken, and the other
+// requests are canceled.
+type HedgePolicy struct {
+	// Delay is the time to wait for a response before sending a hedged
+	// request. When it is zero, the delay is learned from the latencies of
+	// recent requests using Percentile, and requests are not hedged until
+	// enough latencies are collected. The learned delay is at least a
+	// millisecond.
+	Delay time.Duration
+
+	// Percentile is the percentile of recent latencies used as the learned
+	// delay, 0.95 by default.
+	Percentile float64
+
+	// MaxHedges is the maximum number of hedged requests sent for a request,
+	// 1 by default.
+	MaxHedges int
+
+	// Alternates are the base URLs, e.g. `https://replica.example.com`,
+	// hedged requests are sent to in turn, by replacing the scheme and host
+	// of the endpoint. Hedged requests are sent to the endpoint itself when
+	// there are no alternates.
+	Alternates []string
+}
+
+// HedgeStats is the metrics of hedged requests.
+type HedgeStats struct {
+	// Requests is the number of requests eligible for hedging.
+	Requests uint64
+
+	// Hedges is the number of hedged requests sent after the hedging delay.
+	Hedges uint64
+
+	// Replacements is the number of requests sent right away to replace a
+	// failed request, when no other request was in flight.
+	Replacements uint64
+
+	// Wins is the number of requests whose response came from a hedged
+	// request.
+	Wins uint64
+}
+
+// hedger sends the hedged requests and learns the hedging delay.
+type hedger struct {
+	policy HedgePolicy
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+
+	requests     uint64
+	hedges       uint64
+	replacements uint64
+	wins         uint64
+}
+
+func newHedger(policy HedgePolicy) *hedger {
+	if policy.Percentile <= 0 || policy.Percentile > 1 {
+		policy.Percentile = 0.95
+	}
+
+	if policy.MaxHedges <= 0 {
+		policy.MaxHedges = 1
+	}
+
+	return &hedger{policy: policy, latencies: make([]time.Duration, 0, latencySamples)}
+}
+
+// HedgeStats returns the metrics of hedged requests. It is zero when hedging
+// is not enabled.
+func (c *Pantopoda) HedgeStats() HedgeStats {
+	if c.hedger == nil {
+		return HedgeStats{}
+	}
+
+	return HedgeStats{
+		Requests:     atomic.LoadUint64(&c.hedger.requests),
+		Hedges:       atomic.LoadUint64(&c.hedger.hedges),
+		Replacements: atomic.LoadUint64(&c.hedger.replacements),
+		Wins:         atomic.LoadUint64(&c.hedger.wins),
+	}
+}
+
+// hedgeable checks that the request can be hedged, which is only safe for
+// requests without side effects.
+func hedgeable(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// hedgeResult is the result of an original or hedged request.
+type hedgeResult struct {
+	index   int
+	hedged  bool
+	resp    *http.Response
+	body    []byte
+	err     error
+	latency time.Duration
+}
+
+// send sends the request, along with the hedged requests after the hedging
+// delay, and returns the first successful response. When all of them fail,
+// the result of the last one is returned.
+func (h *hedger) send(ctx context.Context, c *Pantopoda, method string, endpoint string, body []byte, header http.Header) (*http.Response, []byte, error) {
+	atomic.AddUint64(&h.requests, 1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, h.policy.MaxHedges+1)
+	launched := 0
+	launch := func(hedged bool) {
+		index := launched
+		launched++
+
+		go func() {
+			start := time.Now()
+			resp, resBody, err := c.send(ctx, method, h.endpoint(endpoint, index), body, header)
+			results <- hedgeResult{index: index, hedged: hedged, resp: resp, body: resBody, err: err, latency: time.Since(start)}
+		}()
+	}
+
+	launch(false)
+	inFlight := 1
+
+	var timer <-chan time.Time
+	delay, ok := h.delay()
+	if ok {
+		t := time.NewTicker(delay)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	var last hedgeResult
+	for inFlight > 0 {
+		select {
+		case <-timer:
+			if launched > h.policy.MaxHedges {
+				timer = nil
+				continue
+			}
+
+			atomic.AddUint64(&h.hedges, 1)
+			launch(true)
+			inFlight++
+		case result := <-results:
+			inFlight--
+			last = result
+
+			if result.err == nil && result.resp.StatusCode < 500 {
+				h.record(result.latency)
+				if result.hedged {
+					atomic.AddUint64(&h.wins, 1)
+				}
+
+				return result.resp, result.body, nil
+			}
+
+			// The failed request is replaced right away, if hedges remain.
+			if inFlight == 0 && launched <= h.policy.MaxHedges && ctx.Err() == nil {
+				atomic.AddUint64(&h.replacements, 1)
+				launch(false)
+				inFlight++
+			}
+		}
+	}
+
+	return last.resp, last.body, last.err
+}
+
+// endpoint returns the endpoint of the request with given index, in which
+// the hedged requests are sent to the alternates in turn.
+func (h *hedger) endpoint(endpoint string, index int) string {
+	if index == 0 || len(h.policy.Alternates) == 0 {
+		return endpoint
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+
+	alternate, err := url.Parse(h.policy.Alternates[(index-1)%len(h.policy.Alternates)])
+	if err != nil {
+		return endpoint
+	}
+
+	u.Scheme, u.Host = alternate.Scheme, alternate.Host
+	return u.String()
+}
+
+// delay returns the hedging delay, and false if requests are not hedged yet.
+func (h *hedger) delay() (time.Duration, bool) {
+	if h.policy.Delay > 0 {
+		return h.policy.Delay, true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) < minLatencySamples {
+		return 0, false
+	}
+
+	sorted := append([]time.Duration(nil), h.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	delay := sorted[int(float64(len(sorted)-1)*h.policy.Percentile)]
+	if delay < minHedgeDelay {
+		delay = minHedgeDelay
+	}
+
+	return delay, true
+}
+
+// record records the latency of a successful request.
+func (h *hedger) record(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) < latencySamples {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % latencySamples
+}
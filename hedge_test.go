@@ -0,0 +1,133 @@
+package pantopoda
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHedgeServer starts a server which responds its name after the delay,
+// or fails with a bad gateway error if it is failing, and counts the
+// requests.
+func newHedgeServer(t *testing.T, name string, delay time.Duration, failing bool) (*httptest.Server, *int64) {
+	t.Helper()
+
+	hits := new(int64)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(hits, 1)
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		if failing {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		w.Write([]byte(name))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, hits
+}
+
+func assertHedgeStats(t *testing.T, c *Pantopoda, want HedgeStats) {
+	t.Helper()
+
+	if got := c.HedgeStats(); got != want {
+		t.Errorf("expected hedge stats %+v, got %+v", want, got)
+	}
+}
+
+func TestHedge(t *testing.T) {
+	primary, _ := newHedgeServer(t, "primary", 200*time.Millisecond, false)
+	alternate, _ := newHedgeServer(t, "alternate", 0, false)
+	c := NewPantopoda(WithHedging(HedgePolicy{Delay: 10 * time.Millisecond, Alternates: []string{alternate.URL}}))
+
+	response, err := c.Get(primary.URL, Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := response.ToString(); got != "alternate" {
+		t.Errorf("expected the response of the hedged request, got %s", got)
+	}
+
+	assertHedgeStats(t, c, HedgeStats{Requests: 1, Hedges: 1, Wins: 1})
+}
+
+func TestHedgeMaxHedges(t *testing.T) {
+	primary, primaryHits := newHedgeServer(t, "primary", 300*time.Millisecond, false)
+	first, firstHits := newHedgeServer(t, "first", 100*time.Millisecond, false)
+	second, secondHits := newHedgeServer(t, "second", 100*time.Millisecond, false)
+	c := NewPantopoda(WithHedging(HedgePolicy{Delay: 10 * time.Millisecond, MaxHedges: 2, Alternates: []string{first.URL, second.URL}}))
+
+	if _, err := c.Get(primary.URL, Request{}); err != nil {
+		t.Fatal(err)
+	}
+
+	hits := atomic.LoadInt64(primaryHits) + atomic.LoadInt64(firstHits) + atomic.LoadInt64(secondHits)
+	if hits != 3 {
+		t.Errorf("expected 3 requests, got %d", hits)
+	}
+
+	assertHedgeStats(t, c, HedgeStats{Requests: 1, Hedges: 2, Wins: 1})
+}
+
+func TestHedgeReplacement(t *testing.T) {
+	primary, _ := newHedgeServer(t, "primary", 0, true)
+	alternate, _ := newHedgeServer(t, "alternate", 0, false)
+	c := NewPantopoda(WithHedging(HedgePolicy{Delay: time.Hour, Alternates: []string{alternate.URL}}))
+
+	response, err := c.Get(primary.URL, Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := response.ToString(); got != "alternate" {
+		t.Errorf("expected the response of the replacement, got %s", got)
+	}
+
+	assertHedgeStats(t, c, HedgeStats{Requests: 1, Replacements: 1})
+}
+
+func TestHedgeLearnedDelay(t *testing.T) {
+	h := newHedger(HedgePolicy{})
+	for i := 0; i < minLatencySamples-1; i++ {
+		h.record(0)
+	}
+	if _, ok := h.delay(); ok {
+		t.Error("expected requests not to be hedged before enough latencies are collected")
+	}
+
+	h.record(0)
+	if delay, ok := h.delay(); !ok || delay != minHedgeDelay {
+		t.Errorf("expected the learned delay to be clamped to %s, got %s", minHedgeDelay, delay)
+	}
+
+	for i := 0; i < latencySamples; i++ {
+		h.record(time.Duration(i+1) * time.Millisecond)
+	}
+	if delay, _ := h.delay(); delay != 121*time.Millisecond {
+		t.Errorf("expected the 95th percentile of latencies, got %s", delay)
+	}
+
+	primary, _ := newHedgeServer(t, "primary", 200*time.Millisecond, false)
+	alternate, _ := newHedgeServer(t, "alternate", 0, false)
+	c := NewPantopoda(WithHedging(HedgePolicy{Alternates: []string{alternate.URL}}))
+	for i := 0; i < minLatencySamples; i++ {
+		c.hedger.record(0)
+	}
+
+	response, err := c.Get(primary.URL, Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := response.ToString(); got != "alternate" {
+		t.Errorf("expected the response of the hedged request, got %s", got)
+	}
+
+	assertHedgeStats(t, c, HedgeStats{Requests: 1, Hedges: 1, Wins: 1})
+}
@@ -1,37 +1,149 @@
 package pantopoda
 
 import (
+	"context"
+	"reflect"
+	"strings"
+
 	"github.com/Kamva/nautilus"
 	"github.com/Kamva/orca"
 	"github.com/Kamva/shark"
 	"gopkg.in/go-playground/validator.v9"
 )
 
-// Validate runs request data validation and returns validation error
-func Validate(r RequestData) ValidationError {
-	validate := orca.GetValidator()
+// Validate runs request data validation and returns validation error. The
+// fields in the error bag are named after their json or form tags, with the
+// path of nested structs and slice indices, e.g. `items[2].price`. Inputs
+// which are not struct or pointer to struct result in a BadRequest error.
+func Validate(r interface{}) ValidationError {
+	return ValidateContext(context.Background(), r)
+}
+
+// ValidateContext runs request data validation like Validate, passing the
+// context to the context-aware rules. When the lookup of a rule fails, it
+// returns a LookupFailed error carrying the lookup error.
+func ValidateContext(ctx context.Context, r interface{}) ValidationError {
+	validate := getValidator()
 	validationError := ValidationError{}
 
-	err := validate.Struct(r)
+	failure := new(lookupFailure)
+	err := validate.StructCtx(context.WithValue(ctx, lookupFailureKey{}, failure), r)
+	if failure.err != nil {
+		return ValidationError{ErrorType: LookupFailed, details: &validationDetails{err: failure.err}}
+	}
+
+	if err == nil {
+		return validationError
+	}
+
+	errs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		validationError.ErrorType = BadRequest
+		return validationError
+	}
+
+	errorBag := shark.NewErrorBag()
+	t, taggable := r.(nautilus.Taggable)
+
+	for _, err := range errs {
+		fieldError := FieldError{
+			Field: fieldPath(reflect.TypeOf(r), err.StructNamespace()),
+			Rule:  err.Tag(),
+			Param: err.Param(),
+			Key:   err.Tag(),
+		}
+		if taggable {
+			fieldError.Key = orca.GetTranslationKey(t, err.StructField(), err.Tag())
+		}
+
+		errorBag.Append(fieldError.Field, fieldError.Key)
+		validationError.addField(fieldError)
+	}
+
+	validationError.ErrorType = RuleViolation
+	validationError.ErrorBag = errorBag
+
+	return validationError
+}
+
+// fieldPath converts the struct namespace of a validation error, such as
+// `Request.Items[2].Price`, into the path of the field in the request payload,
+// such as `items[2].price`.
+func fieldPath(t reflect.Type, namespace string) string {
+	segments := strings.Split(namespace, ".")
+	if len(segments) > 1 {
+		segments = segments[1:]
+	}
+
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		name, indices := segment, ""
+		if i := strings.Index(segment, "["); i >= 0 {
+			name, indices = segment[:i], segment[i:]
+		}
+
+		t = indirect(t)
+		if t != nil && t.Kind() == reflect.Struct {
+			if field, ok := t.FieldByName(name); ok {
+				// Embedded structs without a tag name are flattened in the
+				// payload, so they have no segment in the path.
+				if field.Anonymous && indices == "" && tagName(field) == "" {
+					t = field.Type
+					continue
+				}
 
-	if err != nil {
-		if _, ok := err.(*validator.InvalidValidationError); ok {
-			validationError.ErrorType = BadRequest
+				name, t = payloadName(field), field.Type
+			} else {
+				name, t = nautilus.ToSnake(name), nil
+			}
 		} else {
-			errorBag := shark.NewErrorBag()
-
-			t := r.(nautilus.Taggable)
-			for _, err := range err.(validator.ValidationErrors) {
-				errorBag.Append(
-					nautilus.ToSnake(err.StructField()),
-					orca.GetTranslationKey(t, err.StructField(), err.Tag()),
-				)
+			name = nautilus.ToSnake(name)
+		}
+
+		for i := strings.Count(indices, "["); i > 0 && t != nil; i-- {
+			t = indirect(t)
+			switch t.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				t = t.Elem()
+			default:
+				t = nil
 			}
+		}
+
+		path = append(path, name+indices)
+	}
+
+	return strings.Join(path, ".")
+}
+
+// payloadName returns the name of the field in the request payload, which is
+// taken from its json or form tag, and falls back to the snake case of the
+// field name.
+func payloadName(field reflect.StructField) string {
+	if name := tagName(field); name != "" {
+		return name
+	}
+
+	return nautilus.ToSnake(field.Name)
+}
 
-			validationError.ErrorType = RuleViolation
-			validationError.ErrorBag = errorBag
+// tagName returns the name of the field in its json or form tag, if there is
+// any.
+func tagName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		if name := strings.Split(field.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+			return name
 		}
 	}
 
-	return validationError
+	return ""
+}
+
+// indirect returns the type pointed by t, if t is a pointer.
+func indirect(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
 }
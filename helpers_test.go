@@ -0,0 +1,80 @@
+package pantopoda
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type lineItem struct {
+	ProductID string  `json:"product_id" validate:"required"`
+	Price     float64 `form:"price" validate:"gt=0"`
+}
+
+type audit struct {
+	CreatedBy string `validate:"required"`
+}
+
+type orderRequest struct {
+	audit
+
+	Address  address             `json:"shipping_address"`
+	Billing  *address            `json:"billing"`
+	Items    []lineItem          `json:"items" validate:"dive"`
+	Groups   [][]lineItem        `json:"groups" validate:"dive,dive"`
+	Extras   map[string]lineItem `json:"extras" validate:"dive"`
+	Internal string              `json:"-" validate:"required"`
+}
+
+func TestValidateFieldPaths(t *testing.T) {
+	request := orderRequest{
+		Billing: &address{},
+		Items:   []lineItem{{ProductID: "a", Price: 1}, {Price: -1}},
+		Groups:  [][]lineItem{{{ProductID: "a", Price: 1}}, {{ProductID: "b", Price: 0}}},
+		Extras:  map[string]lineItem{"gift": {ProductID: "c", Price: 0}},
+	}
+
+	validationError := Validate(&request)
+	if validationError.ErrorType != RuleViolation {
+		t.Fatalf("expected a rule violation, got %s", validationError)
+	}
+
+	expected := map[string]string{
+		"created_by":            "required",
+		"shipping_address.city": "required",
+		"billing.city":          "required",
+		"items[1].product_id":   "required",
+		"items[1].price":        "gt",
+		"groups[1][0].price":    "gt",
+		"extras[gift].price":    "gt",
+		"internal":              "required",
+	}
+
+	fields := make(map[string]string)
+	for _, fieldError := range validationError.Fields() {
+		fields[fieldError.Field] = fieldError.Rule
+		if keys := validationError.ErrorBag[fieldError.Field]; len(keys) == 0 {
+			t.Errorf("expected %s in the error bag", fieldError.Field)
+		}
+	}
+
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected field errors %v, got %v", expected, fields)
+	}
+}
+
+func TestValidateNonStruct(t *testing.T) {
+	for _, value := range []interface{}{nil, 1, "a", []orderRequest{}} {
+		if validationError := ValidateContext(context.Background(), value); validationError.ErrorType != BadRequest {
+			t.Errorf("expected %v to be a bad request, got %q", value, validationError.ErrorType)
+		}
+	}
+
+	if validationError := Validate(address{City: "Tehran"}); validationError.Failed() || validationError.Fields() != nil || validationError.Unwrap() != nil {
+		t.Errorf("expected no validation error, got %s", validationError)
+	}
+}
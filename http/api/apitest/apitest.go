@@ -0,0 +1,213 @@
+// Package apitest provides utilities for testing handlers that respond
+// through api.Response, without running a server.
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	code "github.com/Kamva/pantopoda/http"
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/context"
+)
+
+// Result is the decoded outcome of a handler invocation.
+type Result struct {
+	// Status is the status code written by the handler.
+	Status code.StatusCode
+
+	// Headers contains the response headers written by the handler.
+	Headers http.Header
+
+	// Code is the `code` field of the response envelope.
+	Code string
+
+	// Message is the `message` field of the response envelope, or the
+	// `detail` member of problem details.
+	Message string
+
+	// Data is the decoded `data` field of the response envelope.
+	Data interface{}
+
+	// Errors is the decoded `errors` field of the response envelope.
+	Errors interface{}
+
+	// Meta is the decoded `meta` field of the response envelope.
+	Meta interface{}
+
+	// Links is the decoded `links` field of the response envelope.
+	Links interface{}
+
+	// Body is the raw response body.
+	Body []byte
+}
+
+// NewRequest creates a new incoming request for `target` with given body
+// encoded as json. A nil body results in a request without payload.
+func NewRequest(method string, target string, body interface{}) *http.Request {
+	if body == nil {
+		return httptest.NewRequest(method, target, nil)
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		panic(err)
+	}
+
+	req := httptest.NewRequest(method, target, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+// Call invokes the handler with an in-memory iris context serving the given
+// request and returns the decoded result.
+func Call(handler iris.Handler, req *http.Request) Result {
+	recorder := httptest.NewRecorder()
+
+	ctx := context.NewContext(iris.New())
+	ctx.BeginRequest(recorder, req)
+	handler(ctx)
+	ctx.EndRequest()
+
+	return decode(recorder)
+}
+
+// Serve invokes the net/http handler with the given request and returns the
+// decoded result.
+func Serve(handler http.Handler, req *http.Request) Result {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	return decode(recorder)
+}
+
+func decode(recorder *httptest.ResponseRecorder) Result {
+	result := Result{
+		Status:  code.StatusCode(recorder.Code),
+		Headers: recorder.Header(),
+		Body:    recorder.Body.Bytes(),
+	}
+
+	envelope := make(map[string]interface{})
+	if err := json.Unmarshal(result.Body, &envelope); err != nil {
+		return result
+	}
+
+	result.Code, _ = envelope["code"].(string)
+	result.Message, _ = envelope["message"].(string)
+	if detail, ok := envelope["detail"].(string); ok && result.Message == "" {
+		result.Message = detail
+	}
+	result.Data = envelope["data"]
+	result.Errors = envelope["errors"]
+	result.Meta = envelope["meta"]
+	result.Links = envelope["links"]
+
+	return result
+}
+
+// Unmarshal parses the `data` field of the response envelope and stores the
+// result in the value pointed to by v.
+func (r Result) Unmarshal(v interface{}) error {
+	b, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// AssertStatus reports a test error if the response status is not `status`.
+func (r Result) AssertStatus(t testing.TB, status code.StatusCode) bool {
+	t.Helper()
+
+	if r.Status != status {
+		t.Errorf("expected status %d, got %d: %s", status, r.Status, r.Body)
+		return false
+	}
+
+	return true
+}
+
+// AssertCode reports a test error if the envelope code is not `code`.
+func (r Result) AssertCode(t testing.TB, code string) bool {
+	t.Helper()
+
+	if r.Code != code {
+		t.Errorf("expected code %q, got %q", code, r.Code)
+		return false
+	}
+
+	return true
+}
+
+// AssertHeader reports a test error if the response header `key` does not
+// have the given value.
+func (r Result) AssertHeader(t testing.TB, key string, value string) bool {
+	t.Helper()
+
+	if actual := r.Headers.Get(key); actual != value {
+		t.Errorf("expected header %s to be %q, got %q", key, value, actual)
+		return false
+	}
+
+	return true
+}
+
+// AssertValidationError reports a test error if the error bag in the response
+// does not contain the translation key `key` for `field`. The error bag is
+// expected in the format filled by pantopoda.Validate, that is a map of field
+// names to their list of translation keys, inside the `errors` field or the
+// `data` field when there is no `errors`. Errors rendered with both key and
+// message are matched by their key, and errors rendered as messages, which is
+// the default of api.Response.Error, are matched by their message.
+func (r Result) AssertValidationError(t testing.TB, field string, key string) bool {
+	t.Helper()
+
+	for _, k := range r.validationErrors()[field] {
+		if k == key {
+			return true
+		}
+	}
+
+	t.Errorf("expected validation error %q on field %q, got %v", key, field, r.validationErrors())
+	return false
+}
+
+func (r Result) validationErrors() map[string][]string {
+	errorBag := make(map[string][]string)
+
+	bag := r.Errors
+	if bag == nil {
+		bag = r.Data
+	}
+
+	fields, ok := bag.(map[string]interface{})
+	if !ok {
+		return errorBag
+	}
+
+	for field, value := range fields {
+		keys, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, key := range keys {
+			switch k := key.(type) {
+			case string:
+				errorBag[field] = append(errorBag[field], k)
+			case map[string]interface{}:
+				if k, ok := k["key"].(string); ok {
+					errorBag[field] = append(errorBag[field], k)
+				}
+			}
+		}
+	}
+
+	return errorBag
+}
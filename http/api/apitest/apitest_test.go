@@ -0,0 +1,113 @@
+package apitest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	code "github.com/Kamva/pantopoda/http"
+)
+
+// recordingT records the test errors reported by the assertions.
+type recordingT struct {
+	testing.TB
+	errors int
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors++
+}
+
+// respond returns a handler which responds the body with given status.
+func respond(status int, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	})
+}
+
+func TestNewRequest(t *testing.T) {
+	req := NewRequest("POST", "/users?page=2", map[string]string{"name": "alice"})
+	if req.Method != "POST" || req.URL.Query().Get("page") != "2" || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected request %s %s %v", req.Method, req.URL, req.Header)
+	}
+	if body, _ := ioutil.ReadAll(req.Body); string(body) != `{"name":"alice"}` {
+		t.Errorf("unexpected body %s", body)
+	}
+
+	req = NewRequest("GET", "/users", nil)
+	if req.Header.Get("Content-Type") != "" || req.ContentLength != 0 {
+		t.Errorf("expected a request without payload, got %v", req.Header)
+	}
+}
+
+func TestServe(t *testing.T) {
+	body := `{"code":"user_found","message":"found.","data":{"name":"alice"},"meta":{"total":1},"links":{"self":"/users/1"}}`
+	result := Serve(respond(http.StatusOK, body), NewRequest("GET", "/users/1", nil))
+
+	if !result.AssertStatus(t, code.OK) || !result.AssertCode(t, "user_found") || !result.AssertHeader(t, "Content-Type", "application/json") {
+		return
+	}
+	if result.Message != "found." || string(result.Body) != body || result.Meta == nil || result.Links == nil {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	var user struct {
+		Name string `json:"name"`
+	}
+	if err := result.Unmarshal(&user); err != nil || user.Name != "alice" {
+		t.Errorf("unexpected data %+v: %v", user, err)
+	}
+}
+
+func TestServeProblem(t *testing.T) {
+	body := `{"type":"about:blank","title":"Not Found","status":404,"code":"user_not_found","detail":"no such user."}`
+	result := Serve(respond(http.StatusNotFound, body), NewRequest("GET", "/users/1", nil))
+
+	result.AssertStatus(t, code.NotFound)
+	result.AssertCode(t, "user_not_found")
+	if result.Message != "no such user." {
+		t.Errorf("expected the detail as message, got %q", result.Message)
+	}
+}
+
+func TestServeNonJSON(t *testing.T) {
+	result := Serve(respond(http.StatusBadGateway, "bad gateway"), NewRequest("GET", "/", nil))
+
+	result.AssertStatus(t, code.BadGateway)
+	if result.Code != "" || string(result.Body) != "bad gateway" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestAssertValidationError(t *testing.T) {
+	tests := map[string]string{
+		"keys":              `{"errors":{"name":["name.required"]}}`,
+		"keys and messages": `{"errors":{"name":[{"key":"name.required","message":"The name field is required."}]}}`,
+		"data":              `{"data":{"name":["name.required"]}}`,
+	}
+
+	for name, body := range tests {
+		result := Serve(respond(http.StatusUnprocessableEntity, body), NewRequest("POST", "/users", nil))
+		if !result.AssertValidationError(t, "name", "name.required") {
+			t.Logf("case %s", name)
+		}
+
+		recorder := &recordingT{TB: t}
+		if result.AssertValidationError(recorder, "name", "name.min") || recorder.errors != 1 {
+			t.Errorf("%s: expected the missing validation error to be reported", name)
+		}
+	}
+
+	recorder := &recordingT{TB: t}
+	result := Result{Status: code.OK, Code: "ok", Headers: http.Header{}}
+	if result.AssertStatus(recorder, code.Created) || result.AssertCode(recorder, "created") || result.AssertHeader(recorder, "Location", "/users/1") {
+		t.Error("expected the assertions to fail")
+	}
+	if recorder.errors != 3 {
+		t.Errorf("expected 3 reported errors, got %d", recorder.errors)
+	}
+}
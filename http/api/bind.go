@@ -0,0 +1,295 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"mime/multipart"
+	nethttp "net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Kamva/pantopoda"
+	"github.com/kataras/iris"
+)
+
+// UnsupportedMediaTypeCode is the response code of requests whose body has a
+// content type Bind does not decode.
+var UnsupportedMediaTypeCode = "unsupported_media_type"
+
+// errUnsupportedMediaType is returned when the body has a content type which
+// is not decoded.
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// MaxMultipartMemory is the maximum bytes of a multipart request body stored
+// in memory while binding, the rest is stored on disk in temporary files.
+var MaxMultipartMemory int64 = 32 << 20
+
+// fileHeaderType is the type of multipart file fields.
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// Bind populates the request data from the incoming request of iris context
+// and validates it. When binding or validation fails, it responds with the
+// corresponding error Response and returns false, so handlers can return
+// early:
+//
+//	var req CreateUserRequest
+//	if !api.Bind(ctx, &req) {
+//	    return
+//	}
+//
+// The body is decoded into the request data according to its content type;
+// json bodies using `json` tags, and url-encoded and multipart forms using
+// `form` tags. Bodies of other content types are responded with status code
+// 415, while bodies without a content type are ignored. Bodies larger than
+// MaxRequestBodySize are responded with status code 413. Fields with
+// `query`, `param` and `header` tags are populated from the query string,
+// path params and headers respectively.
+func Bind(ctx iris.Context, req pantopoda.RequestData) bool {
+	return bind(NewIrisWriter(ctx), req, ctx.Params().Get)
+}
+
+// BindHTTP is the net/http counterpart of Bind. Since net/http has no path
+// params, fields with `param` tag are left untouched.
+func BindHTTP(w nethttp.ResponseWriter, r *nethttp.Request, req pantopoda.RequestData) bool {
+	return bind(NewHTTPWriter(w, r), req, func(string) string { return "" })
+}
+
+func bind(w Writer, req pantopoda.RequestData, param func(name string) string) bool {
+	if !decompress(w) {
+		return false
+	}
+
+	r := w.Request()
+	if r.Body != nil && MaxRequestBodySize > 0 {
+		r.Body = nethttp.MaxBytesReader(w, r.Body, MaxRequestBodySize)
+	}
+
+	validationError, err := decode(r, req, param)
+
+	var tooLarge *nethttp.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		NewWriterResponse(w).PayloadTooLarge(PayloadTooLargeCode, Payload{
+			Message: "request body is too large.",
+		})
+		return false
+	case errors.Is(err, errUnsupportedMediaType):
+		NewWriterResponse(w).UnsupportedMediaType(UnsupportedMediaTypeCode, Payload{
+			Message: "content type of the request is not supported.",
+		})
+		return false
+	}
+
+	if !validationError.Failed() {
+		validationError = req.Validate()
+	}
+
+	if validationError.Failed() {
+		NewWriterResponse(w).Error(string(validationError.ErrorType), validationError)
+		return false
+	}
+
+	return true
+}
+
+// decode populates the request data pointed by req from the request. The
+// error of reading the body is returned along with the BadRequest error.
+func decode(r *nethttp.Request, req interface{}, param func(name string) string) (pantopoda.ValidationError, error) {
+	badRequest := pantopoda.ValidationError{ErrorType: pantopoda.BadRequest}
+
+	v := reflect.ValueOf(req)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return badRequest, nil
+	}
+
+	if err := decodeBody(r, v.Elem()); err != nil {
+		return badRequest, err
+	}
+
+	sources := map[string]func(name string) []string{
+		"query":  func(name string) []string { return r.URL.Query()[name] },
+		"header": func(name string) []string { return r.Header.Values(name) },
+		"param": func(name string) []string {
+			if value := param(name); value != "" {
+				return []string{value}
+			}
+			return nil
+		},
+	}
+
+	for tag, source := range sources {
+		if err := decodeValues(v.Elem(), tag, source); err != nil {
+			return badRequest, nil
+		}
+	}
+
+	return pantopoda.ValidationError{}, nil
+}
+
+// decodeBody decodes the request body into the struct value according to the
+// content type of the request.
+func decodeBody(r *nethttp.Request, v reflect.Value) error {
+	contentType := r.Header.Get("Content-Type")
+	if r.Body == nil || r.ContentLength == 0 || contentType == "" {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return errUnsupportedMediaType
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return json.NewDecoder(r.Body).Decode(v.Addr().Interface())
+	case mediaType == "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return err
+		}
+
+		return decodeValues(v, "form", func(name string) []string { return r.PostForm[name] })
+	case mediaType == "multipart/form-data":
+		if err := r.ParseMultipartForm(MaxMultipartMemory); err != nil {
+			return err
+		}
+
+		if err := decodeValues(v, "form", func(name string) []string { return r.MultipartForm.Value[name] }); err != nil {
+			return err
+		}
+
+		return decodeFiles(v, r.MultipartForm.File)
+	default:
+		return errUnsupportedMediaType
+	}
+}
+
+// decodeValues sets the fields of struct value having given tag from the
+// values returned by source. Embedded structs are decoded recursively.
+func decodeValues(v reflect.Value, tag string, source func(name string) []string) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := decodeValues(v.Field(i), tag, source); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := tagName(field, tag)
+		if name == "" {
+			continue
+		}
+
+		values := source(name)
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setValue(v.Field(i), values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeFiles sets the multipart file fields of struct value having `form`
+// tag. Embedded structs are decoded recursively.
+func decodeFiles(v reflect.Value, files map[string][]*multipart.FileHeader) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := decodeFiles(v.Field(i), files); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := tagName(field, "form")
+		if name == "" || len(files[name]) == 0 {
+			continue
+		}
+
+		switch {
+		case field.Type == fileHeaderType:
+			v.Field(i).Set(reflect.ValueOf(files[name][0]))
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem() == fileHeaderType:
+			v.Field(i).Set(reflect.ValueOf(files[name]))
+		}
+	}
+
+	return nil
+}
+
+// tagName returns the name of the field in given tag, or empty string if the
+// field does not have the tag or is ignored using `-`.
+func tagName(field reflect.StructField, tag string) string {
+	name := strings.Split(field.Tag.Get(tag), ",")[0]
+	if name == "-" {
+		return ""
+	}
+
+	return name
+}
+
+// setValue converts the values into the type of field and sets it.
+func setValue(field reflect.Value, values []string) error {
+	switch field.Kind() {
+	case reflect.Ptr:
+		value := reflect.New(field.Type().Elem())
+		if err := setValue(value.Elem(), values); err != nil {
+			return err
+		}
+		field.Set(value)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), []string{value}); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+	case reflect.String:
+		field.SetString(values[0])
+	case reflect.Bool:
+		b, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(values[0], 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(values[0], 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(values[0], field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	}
+
+	return nil
+}
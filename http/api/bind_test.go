@@ -0,0 +1,167 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	nethttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Kamva/pantopoda"
+	"github.com/Kamva/pantopoda/http"
+	"github.com/Kamva/pantopoda/http/api/apitest"
+)
+
+type bindPaging struct {
+	Page int `query:"page"`
+}
+
+type bindAttachment struct {
+	Avatar *multipart.FileHeader `form:"avatar"`
+}
+
+type bindRequest struct {
+	bindPaging
+	bindAttachment
+
+	Name  string   `json:"name" form:"name" validate:"required"`
+	Age   int      `json:"age" form:"age"`
+	Tags  []string `json:"tags" query:"tag"`
+	Trace string   `header:"X-Trace"`
+}
+
+func (r *bindRequest) GetTag(field string, key string) string {
+	return ""
+}
+
+func (r *bindRequest) Validate() pantopoda.ValidationError {
+	return pantopoda.Validate(r)
+}
+
+// serveBind binds the request into bindRequest, and responds the bound
+// request on success.
+func serveBind(req *nethttp.Request) (apitest.Result, bindRequest) {
+	var bound bindRequest
+	handler := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if BindHTTP(w, r, &bound) {
+			w.WriteHeader(nethttp.StatusOK)
+		}
+	})
+
+	return apitest.Serve(handler, req), bound
+}
+
+func newBodyRequest(target string, contentType string, body string) *nethttp.Request {
+	req := httptest.NewRequest("POST", target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	return req
+}
+
+func TestBindJSON(t *testing.T) {
+	req := newBodyRequest("/users?page=2&tag=a&tag=b", "application/json; charset=utf-8", `{"name":"alice","age":30}`)
+	req.Header.Set("X-Trace", "abc")
+
+	result, bound := serveBind(req)
+	result.AssertStatus(t, http.OK)
+
+	if bound.Name != "alice" || bound.Age != 30 || bound.Page != 2 || bound.Trace != "abc" || strings.Join(bound.Tags, ",") != "a,b" {
+		t.Errorf("unexpected bound request %+v", bound)
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	form := url.Values{"name": {"alice"}, "age": {"30"}}
+	result, bound := serveBind(newBodyRequest("/users", "application/x-www-form-urlencoded", form.Encode()))
+	result.AssertStatus(t, http.OK)
+
+	if bound.Name != "alice" || bound.Age != 30 {
+		t.Errorf("unexpected bound request %+v", bound)
+	}
+}
+
+func TestBindMultipart(t *testing.T) {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	writer.WriteField("name", "alice")
+	part, _ := writer.CreateFormFile("avatar", "avatar.png")
+	part.Write([]byte("png"))
+	writer.Close()
+
+	result, bound := serveBind(newBodyRequest("/users", writer.FormDataContentType(), body.String()))
+	result.AssertStatus(t, http.OK)
+
+	if bound.Name != "alice" {
+		t.Errorf("unexpected bound request %+v", bound)
+	}
+	if bound.Avatar == nil || bound.Avatar.Filename != "avatar.png" {
+		t.Fatalf("expected the file of the embedded struct to be bound, got %+v", bound.Avatar)
+	}
+
+	file, err := bound.Avatar.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if b, _ := ioutil.ReadAll(file); string(b) != "png" {
+		t.Errorf("unexpected file content %q", b)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    *nethttp.Request
+		status http.StatusCode
+		code   string
+	}{
+		{"validation", newBodyRequest("/users", "application/json", `{"age":30}`), http.UnprocessableEntity, string(pantopoda.RuleViolation)},
+		{"malformed json", newBodyRequest("/users", "application/json", `{"name":`), http.BadRequest, string(pantopoda.BadRequest)},
+		{"invalid query", newBodyRequest("/users?page=x", "application/json", `{"name":"alice"}`), http.BadRequest, string(pantopoda.BadRequest)},
+		{"unsupported content type", newBodyRequest("/users", "text/plain", `name=alice`), http.UnsupportedMediaType, UnsupportedMediaTypeCode},
+		{"invalid content type", newBodyRequest("/users", "application/", `{}`), http.UnsupportedMediaType, UnsupportedMediaTypeCode},
+		{"no content type", newBodyRequest("/users", "", `{"name":"alice"}`), http.UnprocessableEntity, string(pantopoda.RuleViolation)},
+	}
+
+	for _, test := range tests {
+		result, _ := serveBind(test.req)
+		if !result.AssertStatus(t, test.status) || !result.AssertCode(t, test.code) {
+			t.Logf("case %s", test.name)
+		}
+	}
+}
+
+func TestBindSizeLimit(t *testing.T) {
+	defer func(limit int64) { MaxRequestBodySize = limit }(MaxRequestBodySize)
+	MaxRequestBodySize = 64
+
+	large := strings.Repeat("a", 128)
+	b, _ := json.Marshal(map[string]string{"name": large})
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	writer.WriteField("name", large)
+	writer.Close()
+
+	tests := map[string]*nethttp.Request{
+		"json":      newBodyRequest("/users", "application/json", string(b)),
+		"form":      newBodyRequest("/users", "application/x-www-form-urlencoded", url.Values{"name": {large}}.Encode()),
+		"multipart": newBodyRequest("/users", writer.FormDataContentType(), body.String()),
+	}
+
+	for name, req := range tests {
+		result, _ := serveBind(req)
+		if !result.AssertStatus(t, http.PayloadTooLarge) {
+			t.Logf("case %s", name)
+		}
+	}
+
+	result, _ := serveBind(newBodyRequest("/users", "application/json", `{"name":"alice"}`))
+	result.AssertStatus(t, http.OK)
+}
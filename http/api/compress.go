@@ -0,0 +1,216 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Kamva/pantopoda"
+	"github.com/Kamva/pantopoda/compress"
+	"github.com/Kamva/pantopoda/http"
+	"github.com/kataras/iris"
+)
+
+// Response codes of requests with compressed bodies which cannot be decoded.
+var (
+	PayloadTooLargeCode     = "payload_too_large"
+	UnsupportedEncodingCode = "unsupported_content_encoding"
+)
+
+// MaxRequestBodySize is the maximum size of decoded request bodies, in bytes,
+// which protects against decompression bombs, and against clients making
+// the server buffer large bodies. A limit less than or equal to zero means
+// no limit.
+var MaxRequestBodySize int64 = 32 << 20
+
+// CompressionThreshold is the minimum size of response bodies, in bytes, to
+// be compressed. Smaller bodies are not worth the overhead of compression.
+// Compression is disabled when it is less than or equal to zero.
+var CompressionThreshold = 1024
+
+// negotiateEncoding selects the content coding of the response for the
+// Accept-Encoding header of the request. The coding with the highest quality
+// wins, and ties are broken in favor of the preference order of registered
+// codecs. It returns false when the response should not be compressed.
+func negotiateEncoding(header string) (string, bool) {
+	if header == "" {
+		return "", false
+	}
+
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+
+		encoding := strings.ToLower(strings.TrimSpace(params[0]))
+		if encoding == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err == nil && q >= 0 && q <= 1 {
+				quality = q
+			}
+		}
+
+		qualities[encoding] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range compress.Encodings() {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+
+		if ok && quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+
+	if identity, ok := qualities["identity"]; ok && identity >= bestQuality {
+		return "", false
+	}
+
+	return best, best != ""
+}
+
+// compress compresses the encoded response body with the content coding
+// negotiated for the request. It returns nil if the body is not compressed.
+func (r Response) compress(status http.StatusCode, body *bytes.Buffer) *bytes.Buffer {
+	request := r.w.Request()
+	if request == nil || CompressionThreshold <= 0 || body.Len() < CompressionThreshold {
+		return nil
+	}
+
+	if status.IsInformational() || status == http.NoContent || status == http.NotModified {
+		return nil
+	}
+
+	header := r.w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return nil
+	}
+	header.Add("Vary", "Accept-Encoding")
+
+	encoding, ok := negotiateEncoding(request.Header.Get("Accept-Encoding"))
+	if !ok {
+		return nil
+	}
+
+	compressed := getBuffer()
+	if err := compress.Compress(compressed, encoding, body.Bytes()); err != nil {
+		putBuffer(compressed)
+		logger.Printf("error in compressing response payload with %s: %s", encoding, err)
+		return nil
+	}
+
+	header.Set("Content-Encoding", encoding)
+	header.Del("Content-Length")
+
+	return compressed
+}
+
+// Decompress returns an iris middleware which decodes the compressed request
+// bodies, according to their Content-Encoding header. Bind and
+// SchemaValidator decode the request bodies themselves, so it is only needed
+// for handlers reading the body directly.
+func Decompress() iris.Handler {
+	return func(ctx iris.Context) {
+		if decompress(NewIrisWriter(ctx)) {
+			ctx.Next()
+		}
+	}
+}
+
+// DecompressHTTP is the net/http counterpart of Decompress.
+func DecompressHTTP(next nethttp.Handler) nethttp.Handler {
+	return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if decompress(NewHTTPWriter(w, r)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// decompress replaces the compressed request body with its decoded content.
+// When decoding fails, it responds with the corresponding error Response and
+// returns false.
+func decompress(w Writer) bool {
+	r := w.Request()
+
+	encoding := r.Header.Get("Content-Encoding")
+	if encoding == "" || r.Body == nil {
+		return true
+	}
+
+	body, err := compress.Decode(r.Body, encoding, MaxRequestBodySize)
+	switch {
+	case errors.Is(err, compress.ErrTooLarge):
+		NewWriterResponse(w).PayloadTooLarge(PayloadTooLargeCode, Payload{
+			Message: "request body is too large.",
+		})
+		return false
+	case errors.Is(err, compress.ErrUnsupported):
+		NewWriterResponse(w).UnsupportedMediaType(UnsupportedEncodingCode, Payload{
+			Message: "content encoding of the request is not supported.",
+		}, ResponseHeader{"Accept-Encoding": strings.Join(compress.Encodings(), ", ")})
+		return false
+	case err != nil:
+		NewWriterResponse(w).BadRequest(string(pantopoda.BadRequest), Payload{
+			Message: "invalid compressed request body.",
+		})
+		return false
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+
+	return true
+}
+
+// readBody reads the decoded request body, limited to MaxRequestBodySize,
+// and replaces it with a reader of the read body, so that it is read again
+// by the next handlers. When reading fails, it responds with the
+// corresponding error Response and returns false.
+func readBody(w Writer) ([]byte, bool) {
+	if !decompress(w) {
+		return nil, false
+	}
+
+	r := w.Request()
+	if r.Body == nil {
+		return nil, true
+	}
+
+	reader := r.Body
+	if MaxRequestBodySize > 0 {
+		reader = nethttp.MaxBytesReader(w, r.Body, MaxRequestBodySize)
+	}
+
+	body, err := ioutil.ReadAll(reader)
+	var tooLarge *nethttp.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		NewWriterResponse(w).PayloadTooLarge(PayloadTooLargeCode, Payload{
+			Message: "request body is too large.",
+		})
+		return nil, false
+	case err != nil:
+		NewWriterResponse(w).BadRequest(string(pantopoda.BadRequest), Payload{Message: "error in reading request body."})
+		return nil, false
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, true
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Kamva/pantopoda/compress"
+	"github.com/Kamva/pantopoda/http"
+	"github.com/Kamva/pantopoda/http/api/apitest"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                              "",
+		"gzip":                          "gzip",
+		"GZIP, deflate":                 "gzip",
+		"gzip, deflate, br, zstd":       "br",
+		"gzip;q=1, br;q=0.5":            "gzip",
+		"br;q=0, zstd;q=0":              "",
+		"*":                             "br",
+		"*, br;q=0":                     "zstd",
+		"identity":                      "",
+		"gzip;q=0.5, identity":          "",
+		"gzip, identity;q=0.5":          "gzip",
+		"compress, x-unknown":           "",
+		"deflate;q=invalid, gzip;q=0.9": "deflate",
+	}
+
+	for header, want := range tests {
+		if got, ok := negotiateEncoding(header); got != want || ok != (want != "") {
+			t.Errorf("expected the coding of %q to be %q, got %q", header, want, got)
+		}
+	}
+}
+
+// serveCompressed responds the body with the status, for a request with the
+// Accept-Encoding header.
+func serveCompressed(status http.StatusCode, message string, acceptEncoding string) apitest.Result {
+	handler := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		NewHTTPResponse(w, r).Response("ok", status, Payload{Message: message})
+	})
+
+	req := apitest.NewRequest("GET", "/users", nil)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
+	return apitest.Serve(handler, req)
+}
+
+func TestResponseCompression(t *testing.T) {
+	message := strings.Repeat("a", CompressionThreshold)
+	want := `{"code":"ok","message":"` + message + `"}` + "\n"
+
+	for _, encoding := range []string{compress.Gzip, compress.Deflate, compress.Brotli, compress.Zstd} {
+		result := serveCompressed(http.OK, message, encoding)
+		result.AssertHeader(t, "Content-Encoding", encoding)
+		if vary := result.Headers.Values("Vary"); !strings.Contains(strings.Join(vary, ","), "Accept-Encoding") {
+			t.Errorf("%s: expected the response to vary by Accept-Encoding, got %v", encoding, vary)
+		}
+
+		decoded, err := compress.Decode(bytes.NewReader(result.Body), encoding, 0)
+		if err != nil || string(decoded) != want {
+			t.Errorf("%s: expected the body to be compressed, got %v", encoding, err)
+		}
+	}
+
+	tests := []struct {
+		status         http.StatusCode
+		message        string
+		acceptEncoding string
+	}{
+		{http.OK, "small", "gzip"},
+		{http.OK, message, ""},
+		{http.OK, message, "identity"},
+		{http.NoContent, message, "gzip"},
+	}
+
+	for _, test := range tests {
+		result := serveCompressed(test.status, test.message, test.acceptEncoding)
+		if got := result.Headers.Get("Content-Encoding"); got != "" {
+			t.Errorf("expected the response with status %d for %q not to be compressed, got %s", test.status, test.acceptEncoding, got)
+		}
+	}
+
+	defer func(threshold int) { CompressionThreshold = threshold }(CompressionThreshold)
+	CompressionThreshold = 0
+	if got := serveCompressed(http.OK, message, "gzip").Headers.Get("Content-Encoding"); got != "" {
+		t.Errorf("expected the compression to be disabled, got %s", got)
+	}
+}
+
+// serveDecompress serves a request with the body encoded with the content
+// coding, through DecompressHTTP echoing the decoded body.
+func serveDecompress(contentEncoding string, body []byte) apitest.Result {
+	handler := DecompressHTTP(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.Header.Get("Content-Encoding") != "" || r.ContentLength < 0 {
+			w.WriteHeader(nethttp.StatusInternalServerError)
+			return
+		}
+
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write(b)
+	}))
+
+	req := httptest.NewRequest("POST", "/users", bytes.NewReader(body))
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+
+	return apitest.Serve(handler, req)
+}
+
+func TestDecompressHTTP(t *testing.T) {
+	body := []byte(`{"name":"alice"}`)
+
+	for _, encoding := range []string{"", compress.Gzip, compress.Deflate, compress.Brotli, compress.Zstd} {
+		encoded := body
+		if encoding != "" {
+			buf := new(bytes.Buffer)
+			if err := compress.Compress(buf, encoding, body); err != nil {
+				t.Fatal(err)
+			}
+			encoded = buf.Bytes()
+		}
+
+		result := serveDecompress(encoding, encoded)
+		result.AssertStatus(t, http.OK)
+		if !bytes.Equal(result.Body, body) {
+			t.Errorf("%s: expected the body to be decoded, got %s", encoding, result.Body)
+		}
+	}
+
+	result := serveDecompress("compress", body)
+	result.AssertStatus(t, http.UnsupportedMediaType)
+	result.AssertCode(t, UnsupportedEncodingCode)
+	result.AssertHeader(t, "Accept-Encoding", strings.Join(compress.Encodings(), ", "))
+
+	result = serveDecompress(compress.Gzip, body)
+	result.AssertStatus(t, http.BadRequest)
+
+	defer func(size int64) { MaxRequestBodySize = size }(MaxRequestBodySize)
+	MaxRequestBodySize = int64(len(body) - 1)
+
+	buf := new(bytes.Buffer)
+	if err := compress.Compress(buf, compress.Gzip, body); err != nil {
+		t.Fatal(err)
+	}
+
+	result = serveDecompress(compress.Gzip, buf.Bytes())
+	result.AssertStatus(t, http.PayloadTooLarge)
+	result.AssertCode(t, PayloadTooLargeCode)
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vmihailenco/msgpack"
+)
+
+// Encoder is responsible for encoding the response body into a media type.
+type Encoder interface {
+	// ContentType returns the value of Content-Type header for the encoded
+	// response body. Its media type is replaced by the negotiated one, when
+	// the encoder is registered for several media types.
+	ContentType() string
+
+	// Encode writes the encoding of v to w. When pretty is true the encoder
+	// should produce a human readable output, if the format supports it.
+	Encode(w io.Writer, v interface{}, pretty bool) error
+}
+
+// registeredEncoder is an encoder along with the media type it registered for.
+type registeredEncoder struct {
+	mediaType string
+	encoder   Encoder
+}
+
+var encodersMu sync.RWMutex
+
+// encoders is the list of registered encoders in order of preference. The
+// first encoder is used when the request does not state any preference.
+var encoders = []registeredEncoder{
+	{mediaType: "application/json", encoder: JSONEncoder{}},
+	{mediaType: "application/xml", encoder: XMLEncoder{}},
+	{mediaType: "text/xml", encoder: XMLEncoder{}},
+	{mediaType: "application/msgpack", encoder: MsgPackEncoder{}},
+	{mediaType: "application/x-msgpack", encoder: MsgPackEncoder{}},
+	{mediaType: "application/problem+json", encoder: JSONEncoder{}},
+	{mediaType: "application/problem+xml", encoder: XMLEncoder{}},
+}
+
+// RegisterEncoder registers the encoder for given media type. If the media
+// type is already registered, its encoder will be replaced.
+func RegisterEncoder(mediaType string, encoder Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	mediaType = strings.ToLower(mediaType)
+	for i, e := range encoders {
+		if e.mediaType == mediaType {
+			encoders[i].encoder = encoder
+			return
+		}
+	}
+
+	encoders = append(encoders, registeredEncoder{mediaType: mediaType, encoder: encoder})
+}
+
+// defaultEncoder returns the encoder used when the request has no preference,
+// along with its media type.
+func defaultEncoder() (string, Encoder) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	return encoders[0].mediaType, encoders[0].encoder
+}
+
+// JSONEncoder encodes response body as json.
+type JSONEncoder struct{}
+
+// ContentType returns the json content type.
+func (JSONEncoder) ContentType() string {
+	return "application/json; charset=UTF-8"
+}
+
+// Encode writes the json encoding of v to w.
+func (JSONEncoder) Encode(w io.Writer, v interface{}, pretty bool) error {
+	encoder := json.NewEncoder(w)
+	if pretty {
+		encoder.SetIndent("", "  ")
+	}
+
+	return encoder.Encode(v)
+}
+
+// XMLEncoder encodes response body as xml. The body is converted to its json
+// representation first, so field names follow json tags. Objects become
+// elements named after their keys, and array items are encoded as `item`
+// elements, all wrapped inside a `response` root element.
+type XMLEncoder struct{}
+
+// ContentType returns the xml content type.
+func (XMLEncoder) ContentType() string {
+	return "application/xml; charset=UTF-8"
+}
+
+// Encode writes the xml encoding of v to w.
+func (XMLEncoder) Encode(w io.Writer, v interface{}, pretty bool) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err = decoder.Decode(&value); err != nil {
+		return err
+	}
+
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	if pretty {
+		encoder.Indent("", "  ")
+	}
+
+	if err = encodeXMLElement(encoder, "response", value); err != nil {
+		return err
+	}
+
+	return encoder.Flush()
+}
+
+func encodeXMLElement(encoder *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if err := encodeXMLElement(encoder, key, v[key]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := encodeXMLElement(encoder, "item", item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := encoder.EncodeToken(xml.CharData(fmt.Sprint(v))); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// MsgPackEncoder encodes response body as MessagePack. Struct fields are
+// named after their json tags.
+type MsgPackEncoder struct{}
+
+// ContentType returns the MessagePack content type.
+func (MsgPackEncoder) ContentType() string {
+	return "application/msgpack"
+}
+
+// Encode writes the MessagePack encoding of v to w.
+func (MsgPackEncoder) Encode(w io.Writer, v interface{}, pretty bool) error {
+	return msgpack.NewEncoder(w).UseJSONTag(true).SortMapKeys(true).Encode(v)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/Kamva/nautilus"
+)
+
+// maxPooledBuffer is the capacity above which buffers are not returned to the
+// pool, so that a few large responses do not pin memory.
+const maxPooledBuffer = 64 << 10
+
+// bufferPool holds the buffers responses are encoded into. Responses are
+// buffered before writing, so that encoding failures can still be responded
+// with status code 500.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBuffer {
+		bufferPool.Put(buf)
+	}
+}
+
+// envelopeBody is the envelope of payloads as encoded. Its fields are sorted
+// by name, so it is encoded the same as the envelope map.
+type envelopeBody struct {
+	Code    string            `json:"code"`
+	Data    interface{}       `json:"data,omitempty"`
+	Errors  interface{}       `json:"errors,omitempty"`
+	Links   map[string]string `json:"links,omitempty"`
+	Message string            `json:"message,omitempty"`
+	Meta    interface{}       `json:"meta,omitempty"`
+}
+
+// payloadEnvelope is the response body of payloads. It is encoded directly
+// as envelopeBody, unless there are extra fields or fields emitted even if
+// they are empty, which requires the envelope map.
+type payloadEnvelope struct {
+	code    string
+	payload Payload
+	fields  map[string]interface{}
+}
+
+// section is a field of the envelope filled from the payload.
+type section struct {
+	key   string
+	value interface{}
+}
+
+func (e payloadEnvelope) sections() [5]section {
+	return [5]section{
+		{"message", e.payload.Message},
+		{"data", e.payload.Data},
+		{"errors", e.payload.Errors},
+		{"meta", e.payload.Meta},
+		{"links", e.payload.Links},
+	}
+}
+
+func (e payloadEnvelope) withFields(fields map[string]interface{}) responseBody {
+	e.fields = fields
+	return e
+}
+
+func (e payloadEnvelope) envelope() responseJSON {
+	body := responseJSON{"code": e.code}
+
+	reserved := map[string]bool{"code": true}
+	for _, s := range e.sections() {
+		reserved[s.key] = true
+		if !nautilus.Empty(s.value) || e.payload.emits(s.key) {
+			body[s.key] = s.value
+		}
+	}
+
+	for key, value := range e.payload.Extra {
+		if !reserved[key] && (!nautilus.Empty(value) || e.payload.emits(key)) {
+			body[key] = value
+		}
+	}
+
+	for key, value := range e.fields {
+		if _, ok := body[key]; !ok {
+			body[key] = value
+		}
+	}
+
+	return body
+}
+
+func (e payloadEnvelope) value() interface{} {
+	if len(e.fields) > 0 || len(e.payload.Extra) > 0 || len(e.payload.EmitEmpty) > 0 {
+		return e.envelope()
+	}
+
+	return envelopeBody{
+		Code:    e.code,
+		Data:    nonEmpty(e.payload.Data),
+		Errors:  nonEmpty(e.payload.Errors),
+		Links:   e.payload.Links,
+		Message: e.payload.Message,
+		Meta:    nonEmpty(e.payload.Meta),
+	}
+}
+
+// nonEmpty returns nil if the value is empty, so that it is omitted.
+func nonEmpty(value interface{}) interface{} {
+	if nautilus.Empty(value) {
+		return nil
+	}
+
+	return value
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	nethttp "net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Kamva/nautilus"
+	"github.com/Kamva/pantopoda/http/api/apitest"
+)
+
+// serveEnvelope responds the payload with status 200, and returns the body.
+func serveEnvelope(payload Payload) string {
+	handler := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		NewHTTPResponse(w, r).OK("ok", payload)
+	})
+
+	req := apitest.NewRequest("GET", "/users", nil)
+	req.Header.Set("X-Request-ID", "abc")
+
+	return string(apitest.Serve(handler, req).Body)
+}
+
+func TestEnvelope(t *testing.T) {
+	tests := []struct {
+		payload Payload
+		want    string
+	}{
+		{
+			Payload{},
+			`{"code":"ok"}`,
+		},
+		{
+			Payload{Message: "users fetched", Data: []int{}, Meta: map[string]interface{}{}, Links: map[string]string{}},
+			`{"code":"ok","message":"users fetched"}`,
+		},
+		{
+			Payload{
+				Data:  []int{1},
+				Meta:  map[string]interface{}{"total": 1},
+				Links: map[string]string{"next": "/users?page=2"},
+			},
+			`{"code":"ok","data":[1],"links":{"next":"/users?page=2"},"meta":{"total":1}}`,
+		},
+		{
+			Payload{Errors: []map[string]string{{"id": "1", "message": "no such user."}}},
+			`{"code":"ok","errors":[{"id":"1","message":"no such user."}]}`,
+		},
+		{
+			Payload{Data: []int{}, Meta: 0, EmitEmpty: []string{"data", "meta"}},
+			`{"code":"ok","data":[],"meta":0}`,
+		},
+		{
+			Payload{
+				Message:   "users fetched",
+				Extra:     map[string]interface{}{"version": 2, "code": "overridden", "message": "overridden", "empty": "", "cursor": ""},
+				EmitEmpty: []string{"cursor"},
+			},
+			`{"code":"ok","cursor":"","message":"users fetched","version":2}`,
+		},
+	}
+
+	for _, test := range tests {
+		if got := serveEnvelope(test.payload); got != test.want+"\n" {
+			t.Errorf("expected the envelope of %+v to be %s, got %s", test.payload, test.want, got)
+		}
+	}
+}
+
+func TestRegisterField(t *testing.T) {
+	defer func(registered []registeredField) { fields = registered }(fields)
+
+	RegisterField("request_id", func(r *nethttp.Request) interface{} {
+		return "stale"
+	})
+	RegisterField("request_id", func(r *nethttp.Request) interface{} {
+		return r.Header.Get("X-Request-ID")
+	})
+	RegisterField("code", func(r *nethttp.Request) interface{} {
+		return "overridden"
+	})
+	RegisterField("trace_id", func(r *nethttp.Request) interface{} {
+		return nil
+	})
+
+	want := `{"code":"ok","data":[1],"request_id":"abc"}` + "\n"
+	if got := serveEnvelope(Payload{Data: []int{1}}); got != want {
+		t.Errorf("expected the registered fields to be added, got %s", got)
+	}
+
+	want = `{"code":"ok","message":"user","request_id":"extra"}` + "\n"
+	if got := serveEnvelope(Payload{Message: "user", Extra: map[string]interface{}{"request_id": "extra"}}); got != want {
+		t.Errorf("expected the extra fields to take precedence, got %s", got)
+	}
+}
+
+type benchmarkItem struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Tags  []string `json:"tags"`
+}
+
+func benchmarkPayload(items int) Payload {
+	data := make([]benchmarkItem, items)
+	for i := range data {
+		data[i] = benchmarkItem{
+			ID:    i,
+			Name:  "user " + strconv.Itoa(i),
+			Email: "user" + strconv.Itoa(i) + "@example.com",
+			Tags:  []string{"a", "b", "c"},
+		}
+	}
+
+	return Payload{
+		Message: "users fetched",
+		Data:    data,
+		Meta:    map[string]interface{}{"total": items},
+		Links:   map[string]string{"next": "/users?page=2"},
+	}
+}
+
+// mapEnvelope builds the envelope map through mapstructure, as responses were
+// built before encoding the envelope directly.
+func mapEnvelope(code string, payload Payload) responseJSON {
+	body := make(responseJSON)
+	body["code"] = code
+
+	payloadMap := payload.Map()
+	for key, value := range payloadMap {
+		if !nautilus.Empty(value) || payload.emits(key) {
+			body[key] = value
+		}
+	}
+
+	for key, value := range payload.Extra {
+		if _, reserved := payloadMap[key]; reserved || key == "code" {
+			continue
+		}
+
+		if !nautilus.Empty(value) || payload.emits(key) {
+			body[key] = value
+		}
+	}
+
+	return body
+}
+
+func benchmarkMapEnvelope(b *testing.B, payload Payload) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := new(bytes.Buffer)
+		encoded, err := json.Marshal(mapEnvelope("ok", payload))
+		if err != nil {
+			b.Fatal(err)
+		}
+		buf.Write(encoded)
+	}
+}
+
+func benchmarkPooledEnvelope(b *testing.B, payload Payload) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := getBuffer()
+		if err := (JSONEncoder{}).Encode(buf, payloadEnvelope{code: "ok", payload: payload}.value(), false); err != nil {
+			b.Fatal(err)
+		}
+		putBuffer(buf)
+	}
+}
+
+func BenchmarkMapEnvelopeSmall(b *testing.B) {
+	benchmarkMapEnvelope(b, benchmarkPayload(1))
+}
+
+func BenchmarkMapEnvelopeLarge(b *testing.B) {
+	benchmarkMapEnvelope(b, benchmarkPayload(1000))
+}
+
+func BenchmarkPooledEnvelopeSmall(b *testing.B) {
+	benchmarkPooledEnvelope(b, benchmarkPayload(1))
+}
+
+func BenchmarkPooledEnvelopeLarge(b *testing.B) {
+	benchmarkPooledEnvelope(b, benchmarkPayload(1000))
+}
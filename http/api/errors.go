@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/Kamva/pantopoda"
+	"github.com/Kamva/pantopoda/http"
+	"github.com/Kamva/pantopoda/i18n"
+)
+
+// Translator resolves validation errors into messages of a locale.
+type Translator interface {
+	// Translate returns the message of the validation error in the first
+	// supported locale of given locales.
+	Translate(locales []string, fieldError pantopoda.FieldError) string
+}
+
+// TranslatorFunc is an adapter to allow the use of ordinary functions as
+// Translator.
+type TranslatorFunc func(locales []string, fieldError pantopoda.FieldError) string
+
+// Translate calls f(locales, fieldError).
+func (f TranslatorFunc) Translate(locales []string, fieldError pantopoda.FieldError) string {
+	return f(locales, fieldError)
+}
+
+// translator is used to resolve the validation error messages. By default
+// the messages of the built-in catalogs of i18n.Default are responded, which
+// are in English unless the request accepts another supported language.
+var translator Translator = i18n.Default
+
+// SetTranslator sets the translator used to resolve validation errors into
+// messages.
+func SetTranslator(t Translator) {
+	translator = t
+}
+
+// ErrorMessages determines how the validation errors are rendered in the
+// `errors` field of responses.
+type ErrorMessages int
+
+const (
+	// RenderMessages renders the validation errors as translated messages.
+	RenderMessages ErrorMessages = iota
+
+	// RenderKeysAndMessages renders each validation error as an object having
+	// the translation `key` and the translated `message`.
+	RenderKeysAndMessages
+)
+
+// errorMessages is the selected rendering of validation errors.
+var errorMessages = RenderMessages
+
+// SetErrorMessages selects how the validation errors are rendered.
+func SetErrorMessages(m ErrorMessages) {
+	errorMessages = m
+}
+
+// Error generate an error Response from given error.
+//
+// For a pantopoda.ValidationError the Response has status code 422 when the
+// error type is RuleViolation, and 400 otherwise. The validation errors are
+// rendered into the `errors` field, resolved into messages in the locales of
+// the Accept-Language header of the request. Any other error results in a
+// Response with status code 500 without exposing the error, including a
+// pantopoda.ValidationError of LookupFailed type.
+func (r Response) Error(code string, err error, header ...ResponseHeader) {
+	var validationError pantopoda.ValidationError
+	if !errors.As(err, &validationError) {
+		r.InternalServerError(code, Payload{Message: "internal server error."}, header...)
+		return
+	}
+
+	if validationError.ErrorType == pantopoda.LookupFailed {
+		logger.Printf("error in validation lookup: %s", validationError.Unwrap())
+		r.InternalServerError(code, Payload{Message: "internal server error."}, header...)
+		return
+	}
+
+	payload := Payload{
+		Errors: translateErrors(validationError, i18n.RequestLocales(r.w.Request())),
+	}
+
+	if validationError.ErrorType == pantopoda.RuleViolation {
+		r.Response(code, http.UnprocessableEntity, payload, header...)
+	} else {
+		r.Response(code, http.BadRequest, payload, header...)
+	}
+}
+
+// ErrorMessage is the rendering of a validation error having both the
+// translation key and the message.
+type ErrorMessage struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// translateErrors resolves the validation errors into messages of the
+// locales. The field errors are used when available, otherwise the
+// translation keys of the error bag are translated.
+func translateErrors(validationError pantopoda.ValidationError, locales []string) map[string][]interface{} {
+	fieldErrors := validationError.Fields()
+	if len(fieldErrors) == 0 {
+		for field, keys := range validationError.ErrorBag {
+			for _, key := range keys {
+				fieldErrors = append(fieldErrors, pantopoda.FieldError{Field: field, Key: key})
+			}
+		}
+	}
+
+	messages := make(map[string][]interface{}, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		message := translator.Translate(locales, fieldError)
+
+		if errorMessages == RenderKeysAndMessages {
+			messages[fieldError.Field] = append(messages[fieldError.Field], ErrorMessage{Key: fieldError.Key, Message: message})
+		} else {
+			messages[fieldError.Field] = append(messages[fieldError.Field], message)
+		}
+	}
+
+	return messages
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"testing"
+
+	"github.com/Kamva/pantopoda"
+	"github.com/Kamva/pantopoda/http"
+	"github.com/Kamva/pantopoda/http/api/apitest"
+)
+
+type signUpRequest struct {
+	Name string `json:"name" validate:"required"`
+	Age  int    `json:"age" validate:"min=18"`
+}
+
+// failingLookup is a lookup which always fails.
+type failingLookup struct{}
+
+func (failingLookup) Exists(ctx context.Context, param string, value interface{}) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+type lookupRequest struct {
+	Email string `json:"email" validate:"test_failing_lookup"`
+}
+
+func init() {
+	pantopoda.RegisterLookupRule("test_failing_lookup", failingLookup{})
+}
+
+// serveError responds the error using Response.Error, for a request with
+// the Accept-Language header.
+func serveError(err error, acceptLanguage string) apitest.Result {
+	handler := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		NewHTTPResponse(w, r).Error("invalid_request", err)
+	})
+
+	req := apitest.NewRequest("POST", "/users", nil)
+	if acceptLanguage != "" {
+		req.Header.Set("Accept-Language", acceptLanguage)
+	}
+
+	return apitest.Serve(handler, req)
+}
+
+func assertErrors(t *testing.T, result apitest.Result, want string) {
+	t.Helper()
+
+	if got := fmt.Sprint(result.Errors); got != want {
+		t.Errorf("expected errors %s, got %s", want, got)
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	err := pantopoda.Validate(signUpRequest{Age: 10})
+
+	result := serveError(err, "")
+	result.AssertStatus(t, http.UnprocessableEntity)
+	result.AssertCode(t, "invalid_request")
+	assertErrors(t, result, "map[age:[The age must be at least 18.] name:[The name field is required.]]")
+
+	assertErrors(t, serveError(err, "de, fa;q=0.8"), "map[age:[age باید حداقل 18 باشد.] name:[فیلد name الزامی است.]]")
+
+	unknownRule := pantopoda.ValidationError{ErrorType: pantopoda.RuleViolation, ErrorBag: map[string][]string{"name": {"name.unknown"}}}
+	assertErrors(t, serveError(unknownRule, ""), "map[name:[The name field is invalid.]]")
+
+	badRequest := pantopoda.ValidationError{ErrorType: pantopoda.BadRequest}
+	serveError(badRequest, "").AssertStatus(t, http.BadRequest)
+}
+
+func TestResponseErrorRendering(t *testing.T) {
+	defer SetErrorMessages(RenderMessages)
+	defer SetTranslator(translator)
+
+	SetErrorMessages(RenderKeysAndMessages)
+	SetTranslator(TranslatorFunc(func(locales []string, fieldError pantopoda.FieldError) string {
+		return fmt.Sprintf("%v %s %s", locales, fieldError.Field, fieldError.Rule)
+	}))
+
+	result := serveError(pantopoda.Validate(signUpRequest{Name: "alice", Age: 10}), "en-US")
+	result.AssertValidationError(t, "age", "min")
+	assertErrors(t, result, "map[age:[map[key:min message:[en-us] age min]]]")
+}
+
+func TestResponseErrorInternal(t *testing.T) {
+	tests := map[string]error{
+		"unknown error":  errors.New("connection refused"),
+		"lookup failure": pantopoda.Validate(lookupRequest{Email: "a@example.com"}),
+	}
+
+	for name, err := range tests {
+		result := serveError(err, "")
+		if !result.AssertStatus(t, http.InternalServerError) || result.Message != "internal server error." {
+			t.Errorf("%s: expected the error not to be exposed, got %s", name, result.Body)
+		}
+	}
+}
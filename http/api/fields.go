@@ -0,0 +1,64 @@
+package api
+
+import (
+	nethttp "net/http"
+	"sync"
+)
+
+// FieldFunc returns the value of a top-level field of the response body for
+// the request. A nil value omits the field. The request is nil if the
+// response writer has no request.
+type FieldFunc func(r *nethttp.Request) interface{}
+
+// registeredField is a top-level field along with the function returning its
+// value.
+type registeredField struct {
+	name  string
+	value FieldFunc
+}
+
+var fieldsMu sync.RWMutex
+
+// fields is the list of registered top-level fields in order of registration.
+var fields = make([]registeredField, 0)
+
+// RegisterField registers a top-level field which is added to every response
+// body, e.g. `request_id` or `timestamp`. If the field is already registered,
+// its function will be replaced. Fields already present in the body, such as
+// `code` and `data`, are never overridden.
+//
+//	api.RegisterField("request_id", func(r *http.Request) interface{} {
+//		return r.Header.Get("X-Request-ID")
+//	})
+func RegisterField(name string, value FieldFunc) {
+	fieldsMu.Lock()
+	defer fieldsMu.Unlock()
+
+	for i, f := range fields {
+		if f.name == name {
+			fields[i].value = value
+			return
+		}
+	}
+
+	fields = append(fields, registeredField{name: name, value: value})
+}
+
+// fieldValues returns the values of the registered fields for the request.
+func fieldValues(r *nethttp.Request) map[string]interface{} {
+	fieldsMu.RLock()
+	defer fieldsMu.RUnlock()
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	values := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		if value := f.value(r); value != nil {
+			values[f.name] = value
+		}
+	}
+
+	return values
+}
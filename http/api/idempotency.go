@@ -0,0 +1,296 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	nethttp "net/http"
+	"sync"
+	"time"
+
+	"github.com/kataras/iris"
+)
+
+// IdempotencyKeyHeader is the header carrying the idempotency key of requests.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Response codes of requests which cannot be processed due to their
+// idempotency key.
+var (
+	IdempotencyInFlightCode   = "idempotency_key_in_flight"
+	IdempotencyMismatchCode   = "idempotency_key_mismatch"
+	IdempotencyStoreErrorCode = "idempotency_store_error"
+)
+
+// IdempotencyEntry is the state of an idempotency key in the store.
+type IdempotencyEntry struct {
+	// Fingerprint identifies the request the key is used for, so that reuse
+	// of the key for a different request can be detected.
+	Fingerprint string
+
+	// Completed is true when the response of the request is stored.
+	Completed bool
+
+	// Status, Header and Body are the stored response.
+	Status int
+	Header nethttp.Header
+	Body   []byte
+}
+
+// IdempotencyStore stores the responses of requests by their idempotency key.
+// Implementations must be safe for concurrent use, and reservation must be
+// atomic across the instances of a service sharing the store.
+type IdempotencyStore interface {
+	// Reserve reserves the key for the request with given fingerprint for
+	// the ttl duration, and returns true. If the key is already reserved, it
+	// returns the existing entry and false.
+	Reserve(key string, fingerprint string, ttl time.Duration) (IdempotencyEntry, bool, error)
+
+	// Complete stores the response of the reserved key for the ttl duration.
+	Complete(key string, entry IdempotencyEntry, ttl time.Duration) error
+
+	// Release removes the reservation of the key, so that the request can be
+	// retried.
+	Release(key string) error
+}
+
+// IdempotencyConfig configures the idempotency middleware.
+type IdempotencyConfig struct {
+	// Store is the store of responses, an in-memory store by default.
+	Store IdempotencyStore
+
+	// TTL is how long the responses are kept, 24 hours by default.
+	TTL time.Duration
+}
+
+// Idempotency returns an iris middleware which makes the requests carrying
+// Idempotency-Key header safe to retry. The response of the first request
+// with a key, produced through Response, is stored and replayed for the
+// repeats of the request, unless it is a server error. Repeats received
+// while the first request is in flight are responded with status code 409,
+// and reusing the key for a different request is responded with status code
+// 422. Requests without the header are passed through, and the bodies of
+// requests with the header larger than MaxRequestBodySize are responded with
+// status code 413.
+func Idempotency(config IdempotencyConfig) iris.Handler {
+	config = config.withDefaults()
+
+	return func(ctx iris.Context) {
+		idempotent(NewIrisWriter(ctx), config, func(r *nethttp.Request) {
+			ctx.ResetRequest(r)
+			ctx.Next()
+		})
+	}
+}
+
+// IdempotencyHTTP is the net/http counterpart of Idempotency.
+func IdempotencyHTTP(config IdempotencyConfig) func(next nethttp.Handler) nethttp.Handler {
+	config = config.withDefaults()
+
+	return func(next nethttp.Handler) nethttp.Handler {
+		return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			idempotent(NewHTTPWriter(w, r), config, func(r *nethttp.Request) {
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+}
+
+func (c IdempotencyConfig) withDefaults() IdempotencyConfig {
+	if c.Store == nil {
+		c.Store = NewMemoryIdempotencyStore()
+	}
+
+	if c.TTL <= 0 {
+		c.TTL = 24 * time.Hour
+	}
+
+	return c
+}
+
+func idempotent(w Writer, config IdempotencyConfig, next func(r *nethttp.Request)) {
+	r := w.Request()
+
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" {
+		next(r)
+		return
+	}
+
+	body, ok := readBody(w)
+	if !ok {
+		return
+	}
+
+	fingerprint := fingerprint(r, body)
+
+	entry, reserved, err := config.Store.Reserve(key, fingerprint, config.TTL)
+	if err != nil {
+		logger.Printf("error in reserving idempotency key: %s", err)
+		NewWriterResponse(w).Error(IdempotencyStoreErrorCode, err)
+		return
+	}
+
+	if !reserved {
+		replay(w, entry, fingerprint)
+		return
+	}
+
+	rec := new(recorder)
+	completed := false
+	defer func() {
+		if !completed {
+			_ = config.Store.Release(key)
+		}
+	}()
+
+	next(r.WithContext(context.WithValue(r.Context(), recorderKey{}, rec)))
+
+	// Server errors are not stored, so that the request can be retried.
+	if entry, ok := rec.entry(); ok && entry.Status < 500 {
+		entry.Fingerprint = fingerprint
+		if err := config.Store.Complete(key, entry, config.TTL); err != nil {
+			logger.Printf("error in storing idempotent response: %s", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replay responds the stored response of the idempotency key.
+func replay(w Writer, entry IdempotencyEntry, fingerprint string) {
+	switch {
+	case entry.Fingerprint != fingerprint:
+		NewWriterResponse(w).UnprocessableEntity(IdempotencyMismatchCode, Payload{
+			Message: "idempotency key is already used for a different request.",
+		})
+	case !entry.Completed:
+		NewWriterResponse(w).Conflict(IdempotencyInFlightCode, Payload{
+			Message: "a request with the same idempotency key is in progress.",
+		}, ResponseHeader{"Retry-After": "1"})
+	default:
+		for key, values := range entry.Header {
+			w.Header()[key] = append([]string(nil), values...)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+
+		w.WriteHeader(entry.Status)
+		_, _ = w.Write(entry.Body)
+	}
+}
+
+// fingerprint identifies the request by its method, path and body.
+func fingerprint(r *nethttp.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorderKey is the context key of the recorder of requests.
+type recorderKey struct{}
+
+// recorder records the response written through Response.
+type recorder struct {
+	mu       sync.Mutex
+	recorded bool
+	status   int
+	header   nethttp.Header
+	body     []byte
+}
+
+// recorderOf returns the recorder of the request, if there is any.
+func recorderOf(r *nethttp.Request) *recorder {
+	if r == nil {
+		return nil
+	}
+
+	rec, _ := r.Context().Value(recorderKey{}).(*recorder)
+	return rec
+}
+
+func (rec *recorder) record(status int, header nethttp.Header, body []byte) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.recorded = true
+	rec.status = status
+	rec.header = header.Clone()
+	rec.body = append([]byte(nil), body...)
+}
+
+func (rec *recorder) entry() (IdempotencyEntry, bool) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return IdempotencyEntry{
+		Completed: true,
+		Status:    rec.status,
+		Header:    rec.header,
+		Body:      rec.body,
+	}, rec.recorded
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore. It is only
+// suitable for services running a single instance.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+type memoryEntry struct {
+	IdempotencyEntry
+	expiresAt time.Time
+}
+
+// NewMemoryIdempotencyStore generate new in-memory idempotency store.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: make(map[string]memoryEntry)}
+}
+
+// Reserve reserves the key, unless it is already reserved and not expired.
+func (s *MemoryIdempotencyStore) Reserve(key string, fingerprint string, ttl time.Duration) (IdempotencyEntry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, e := range s.entries {
+			if now.After(e.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) {
+		return e.IdempotencyEntry, false, nil
+	}
+
+	s.entries[key] = memoryEntry{
+		IdempotencyEntry: IdempotencyEntry{Fingerprint: fingerprint},
+		expiresAt:        now.Add(ttl),
+	}
+
+	return IdempotencyEntry{}, true, nil
+}
+
+// Complete stores the response of the key.
+func (s *MemoryIdempotencyStore) Complete(key string, entry IdempotencyEntry, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = memoryEntry{IdempotencyEntry: entry, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// Release removes the key.
+func (s *MemoryIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"errors"
+	nethttp "net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Kamva/pantopoda/http"
+	"github.com/Kamva/pantopoda/http/api/apitest"
+)
+
+// idempotencyServer serves the requests through IdempotencyHTTP, counting the
+// requests reaching the handler.
+type idempotencyServer struct {
+	handler nethttp.Handler
+	hits    int64
+	status  http.StatusCode
+}
+
+func newIdempotencyServer(config IdempotencyConfig, status http.StatusCode) *idempotencyServer {
+	s := &idempotencyServer{status: status}
+	s.handler = IdempotencyHTTP(config)(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		hits := atomic.AddInt64(&s.hits, 1)
+		NewHTTPResponse(w, r).Response("user_created", s.status, Payload{
+			Data: map[string]interface{}{"hits": hits},
+		}, ResponseHeader{"Location": "/users/1"})
+	}))
+
+	return s
+}
+
+func (s *idempotencyServer) serve(key string, body interface{}) apitest.Result {
+	req := apitest.NewRequest("POST", "/users", body)
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+
+	return apitest.Serve(s.handler, req)
+}
+
+func TestIdempotency(t *testing.T) {
+	s := newIdempotencyServer(IdempotencyConfig{}, http.Created)
+	body := map[string]string{"name": "alice"}
+
+	first := s.serve("key", body)
+	first.AssertStatus(t, http.Created)
+
+	replayed := s.serve("key", body)
+	replayed.AssertStatus(t, http.Created)
+	replayed.AssertHeader(t, "Idempotent-Replayed", "true")
+	replayed.AssertHeader(t, "Location", "/users/1")
+	if string(replayed.Body) != string(first.Body) {
+		t.Errorf("expected the response to be replayed, got %s", replayed.Body)
+	}
+
+	mismatch := s.serve("key", map[string]string{"name": "bob"})
+	mismatch.AssertStatus(t, http.UnprocessableEntity)
+	mismatch.AssertCode(t, IdempotencyMismatchCode)
+
+	s.serve("", body).AssertStatus(t, http.Created)
+	s.serve("", body).AssertStatus(t, http.Created)
+	s.serve("other", body).AssertHeader(t, "Idempotent-Replayed", "")
+
+	if hits := atomic.LoadInt64(&s.hits); hits != 4 {
+		t.Errorf("expected 4 requests to reach the handler, got %d", hits)
+	}
+}
+
+func TestIdempotencyServerError(t *testing.T) {
+	s := newIdempotencyServer(IdempotencyConfig{}, http.InternalServerError)
+	body := map[string]string{"name": "alice"}
+
+	s.serve("key", body).AssertStatus(t, http.InternalServerError)
+	s.status = http.Created
+	s.serve("key", body).AssertHeader(t, "Idempotent-Replayed", "")
+	s.serve("key", body).AssertHeader(t, "Idempotent-Replayed", "true")
+
+	if hits := atomic.LoadInt64(&s.hits); hits != 2 {
+		t.Errorf("expected the server error not to be stored, got %d hits", hits)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := IdempotencyHTTP(IdempotencyConfig{})(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		close(started)
+		<-release
+		NewHTTPResponse(w, r).Created("user_created", Payload{})
+	}))
+
+	newRequest := func() *nethttp.Request {
+		req := apitest.NewRequest("POST", "/users", map[string]string{"name": "alice"})
+		req.Header.Set(IdempotencyKeyHeader, "key")
+		return req
+	}
+
+	done := make(chan apitest.Result)
+	go func() {
+		done <- apitest.Serve(handler, newRequest())
+	}()
+	<-started
+
+	result := apitest.Serve(handler, newRequest())
+	result.AssertStatus(t, http.Conflict)
+	result.AssertCode(t, IdempotencyInFlightCode)
+	result.AssertHeader(t, "Retry-After", "1")
+
+	close(release)
+	(<-done).AssertStatus(t, http.Created)
+}
+
+// failingStore is an IdempotencyStore which cannot be reached.
+type failingStore struct {
+	IdempotencyStore
+}
+
+func (failingStore) Reserve(key string, fingerprint string, ttl time.Duration) (IdempotencyEntry, bool, error) {
+	return IdempotencyEntry{}, false, errors.New("store is unavailable")
+}
+
+func TestIdempotencyStoreError(t *testing.T) {
+	s := newIdempotencyServer(IdempotencyConfig{Store: failingStore{}}, http.Created)
+
+	result := s.serve("key", map[string]string{"name": "alice"})
+	result.AssertStatus(t, http.InternalServerError)
+	result.AssertCode(t, IdempotencyStoreErrorCode)
+
+	if hits := atomic.LoadInt64(&s.hits); hits != 0 {
+		t.Errorf("expected the request not to reach the handler, got %d hits", hits)
+	}
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+
+	if _, reserved, _ := store.Reserve("key", "a", time.Hour); !reserved {
+		t.Fatal("expected the key to be reserved")
+	}
+	if entry, reserved, _ := store.Reserve("key", "b", time.Hour); reserved || entry.Fingerprint != "a" || entry.Completed {
+		t.Errorf("expected the in-flight entry, got %+v", entry)
+	}
+
+	_ = store.Complete("key", IdempotencyEntry{Fingerprint: "a", Completed: true, Status: 201}, time.Hour)
+	if entry, reserved, _ := store.Reserve("key", "a", time.Hour); reserved || entry.Status != 201 {
+		t.Errorf("expected the completed entry, got %+v", entry)
+	}
+
+	_ = store.Release("key")
+	if _, reserved, _ := store.Reserve("key", "a", time.Hour); !reserved {
+		t.Error("expected the released key to be reserved again")
+	}
+
+	if _, reserved, _ := store.Reserve("expired", "a", -time.Second); !reserved {
+		t.Fatal("expected the key to be reserved")
+	}
+	if _, reserved, _ := store.Reserve("expired", "b", time.Hour); !reserved {
+		t.Error("expected the expired key to be reserved again")
+	}
+}
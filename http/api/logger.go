@@ -0,0 +1,20 @@
+package api
+
+import (
+	"log"
+	"os"
+)
+
+// Logger logs the errors which cannot be reported to the client, such as
+// failures in encoding response payloads.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// logger is used to log the errors. It logs into stderr by default.
+var logger Logger = log.New(os.Stderr, "api: ", log.LstdFlags)
+
+// SetLogger sets the logger used to log the errors.
+func SetLogger(l Logger) {
+	logger = l
+}
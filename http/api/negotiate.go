@@ -0,0 +1,144 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// NotAcceptableCode is the response code used when none of the media types
+// accepted by the request has a registered encoder.
+var NotAcceptableCode = "not_acceptable"
+
+// mediaRange is a media range of the Accept header along with its quality.
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+// specificity returns how specific the media range is. Exact media types are
+// more specific than `type/*` ranges, which are more specific than `*/*`.
+func (m mediaRange) specificity() int {
+	switch {
+	case m.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(m.mediaType, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// matches checks that media range includes the given media type.
+func (m mediaRange) matches(mediaType string) bool {
+	switch m.specificity() {
+	case 0:
+		return true
+	case 1:
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(m.mediaType, "*"))
+	default:
+		return m.mediaType == mediaType
+	}
+}
+
+// parseAccept parses the Accept header value into its media ranges.
+func parseAccept(header string) []mediaRange {
+	ranges := make([]mediaRange, 0)
+
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err == nil && q >= 0 && q <= 1 {
+				quality = q
+			}
+		}
+
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+
+	return ranges
+}
+
+// negotiate selects the encoder for the Accept header of the request, and
+// returns it along with the negotiated media type. The quality of each
+// registered media type is taken from the most specific range matching it,
+// and the media type with the highest quality wins. Ties are broken in favor
+// of the earlier registered encoder. It returns false when none of the
+// registered media types is acceptable.
+func negotiate(header string) (string, Encoder, bool) {
+	if strings.TrimSpace(header) == "" {
+		mediaType, encoder := defaultEncoder()
+		return mediaType, encoder, true
+	}
+
+	ranges := parseAccept(header)
+
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	var selected *registeredEncoder
+	bestQuality := 0.0
+	for i, e := range encoders {
+		quality, specificity := 0.0, -1
+		for _, r := range ranges {
+			if r.matches(e.mediaType) && r.specificity() > specificity {
+				quality, specificity = r.quality, r.specificity()
+			}
+		}
+
+		if quality > bestQuality {
+			selected, bestQuality = &encoders[i], quality
+		}
+	}
+
+	if selected == nil {
+		return "", nil, false
+	}
+
+	return selected.mediaType, selected.encoder, true
+}
+
+// contentType returns the Content-Type of the response encoded by the
+// encoder, which is the negotiated media type along with the parameters of
+// the encoder content type, such as its charset.
+func contentType(mediaType string, encoder Encoder) string {
+	parts := strings.SplitN(encoder.ContentType(), ";", 2)
+	if mediaType != "" {
+		parts[0] = mediaType
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// wantsPretty checks that the request asked for a human readable output using
+// the `pretty` query param.
+func wantsPretty(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+
+	values, ok := r.URL.Query()["pretty"]
+	if !ok {
+		return false
+	}
+
+	if len(values) == 0 || values[0] == "" {
+		return true
+	}
+
+	pretty, err := strconv.ParseBool(values[0])
+	return err == nil && pretty
+}
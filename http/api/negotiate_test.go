@@ -0,0 +1,91 @@
+package api
+
+import (
+	nethttp "net/http"
+	"strings"
+	"testing"
+
+	"github.com/Kamva/pantopoda/http"
+	"github.com/Kamva/pantopoda/http/api/apitest"
+)
+
+// serveOK responds a payload with OK status, for a request with the Accept
+// header.
+func serveOK(accept string, target string) apitest.Result {
+	handler := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		NewHTTPResponse(w, r).OK("user_found", Payload{Data: map[string]string{"name": "alice"}})
+	})
+
+	req := apitest.NewRequest("GET", target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	return apitest.Serve(handler, req)
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]string{
+		"":                                      "application/json",
+		"*/*":                                   "application/json",
+		"application/xml":                       "application/xml",
+		"text/xml":                              "text/xml",
+		"text/*":                                "text/xml",
+		"application/x-msgpack":                 "application/x-msgpack",
+		"application/xml;q=0.5, */*;q=0.1":      "application/xml",
+		"application/json;q=0.5, text/xml":      "text/xml",
+		"application/*;q=0.2, application/json": "application/json",
+		"application/json;q=0, */*":             "application/xml",
+		"text/html":                             "",
+		"application/json;q=0":                  "",
+	}
+
+	for accept, want := range tests {
+		mediaType, _, ok := negotiate(accept)
+		if ok != (want != "") || mediaType != want {
+			t.Errorf("expected %q to negotiate %q, got %q", accept, want, mediaType)
+		}
+	}
+}
+
+func TestResponseContentType(t *testing.T) {
+	tests := map[string]string{
+		"":                      "application/json; charset=UTF-8",
+		"text/xml":              "text/xml; charset=UTF-8",
+		"application/xml":       "application/xml; charset=UTF-8",
+		"application/x-msgpack": "application/x-msgpack",
+	}
+
+	for accept, want := range tests {
+		result := serveOK(accept, "/users/1")
+		result.AssertStatus(t, http.OK)
+		result.AssertHeader(t, "Content-Type", want)
+		result.AssertHeader(t, "Vary", "Accept")
+	}
+
+	if body := string(serveOK("text/xml", "/users/1").Body); !strings.Contains(body, "<response><code>user_found</code><data><name>alice</name></data></response>") {
+		t.Errorf("unexpected xml body %s", body)
+	}
+}
+
+func TestResponseNotAcceptable(t *testing.T) {
+	result := serveOK("text/html", "/users/1")
+	result.AssertStatus(t, http.NotAcceptable)
+	result.AssertCode(t, NotAcceptableCode)
+	result.AssertHeader(t, "Content-Type", "application/json; charset=UTF-8")
+}
+
+func TestResponsePretty(t *testing.T) {
+	tests := map[string]bool{
+		"/users/1":              false,
+		"/users/1?pretty":       true,
+		"/users/1?pretty=true":  true,
+		"/users/1?pretty=false": false,
+	}
+
+	for target, pretty := range tests {
+		if body := string(serveOK("", target).Body); strings.Contains(body, "\n  ") != pretty {
+			t.Errorf("expected the body of %s to be pretty %t, got %s", target, pretty, body)
+		}
+	}
+}
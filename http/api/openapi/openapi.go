@@ -0,0 +1,294 @@
+// Package openapi generates OpenAPI 3 documents for handlers responding
+// through api.Response.
+//
+// Routes are recorded in a Registry along with their request data type and
+// the payload data type of each response status. Request data types are
+// described using the same tags api.Bind reads; `json` tagged fields form the
+// request body, while `param`, `query` and `header` tagged fields form the
+// parameters. The `validate` tags are translated into schema constraints.
+package openapi
+
+import (
+	"encoding/json"
+	nethttp "net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Kamva/pantopoda/http"
+	"github.com/kataras/iris"
+)
+
+// irisParam matches the iris path params, such as `{id:uint64}`.
+var irisParam = regexp.MustCompile(`\{([^:}]+)(:[^}]*)?\}`)
+
+// Route describes an API route.
+type Route struct {
+	// Method is the HTTP method of the route.
+	Method string
+
+	// Path is the path of the route, iris path params are supported.
+	Path string
+
+	// Summary is a short summary of what the route does.
+	Summary string
+
+	// Tags are used to group the routes in the document.
+	Tags []string
+
+	// Request is a value of the request data type of the route, e.g.
+	// CreateUserRequest{}. It can be nil for routes without request data.
+	Request interface{}
+
+	// Responses maps the response status codes to a value of their payload
+	// data type. A nil value means the response has no data.
+	Responses map[http.StatusCode]interface{}
+}
+
+// Registry records the routes of an API to generate its OpenAPI document.
+type Registry struct {
+	mu      sync.RWMutex
+	title   string
+	version string
+	routes  []Route
+}
+
+// NewRegistry generate new registry for the API with given title and version.
+func NewRegistry(title string, version string) *Registry {
+	return &Registry{title: title, version: version}
+}
+
+// Add records the route in the registry.
+func (r *Registry) Add(route Route) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.routes = append(r.routes, route)
+	return r
+}
+
+// Handle registers the handlers for the route on the iris party and records
+// the route in the registry.
+func (r *Registry) Handle(party iris.Party, route Route, handlers ...iris.Handler) {
+	party.Handle(route.Method, route.Path, handlers...)
+	r.Add(route)
+}
+
+// Serve registers a handler responding the OpenAPI document at given path on
+// the iris party.
+func (r *Registry) Serve(party iris.Party, path string) {
+	party.Get(path, func(ctx iris.Context) {
+		r.ServeHTTP(ctx.ResponseWriter(), ctx.Request())
+	})
+}
+
+// ServeHTTP responds the OpenAPI document as json.
+func (r *Registry) ServeHTTP(w nethttp.ResponseWriter, _ *nethttp.Request) {
+	b, err := json.Marshal(r.Document())
+	if err != nil {
+		nethttp.Error(w, err.Error(), nethttp.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	_, _ = w.Write(b)
+}
+
+// Document generates the OpenAPI document of the recorded routes.
+func (r *Registry) Document() map[string]interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	generator := newSchemaGenerator(map[string]Schema{
+		"Envelope": envelopeSchema(),
+	})
+
+	paths := make(map[string]map[string]interface{})
+	for _, route := range r.routes {
+		path := irisParam.ReplaceAllString(route.Path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+
+		paths[path][strings.ToLower(route.Method)] = generator.operation(route)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   r.title,
+			"version": r.version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": generator.components,
+		},
+	}
+}
+
+// envelopeSchema returns the schema of the `code`, `message`, `data`
+// envelope of api.Response, along with its optional sections.
+func envelopeSchema() Schema {
+	return Schema{
+		"type":     "object",
+		"required": []string{"code"},
+		"properties": Schema{
+			"code":    Schema{"type": "string"},
+			"message": Schema{"type": "string"},
+			"data":    Schema{},
+			// The errors are messages, or objects having the key and the
+			// message with api.RenderKeysAndMessages.
+			"errors": Schema{
+				"type": "object",
+				"additionalProperties": Schema{
+					"type": "array",
+					"items": Schema{
+						"oneOf": []Schema{
+							{"type": "string"},
+							{
+								"type":     "object",
+								"required": []string{"key", "message"},
+								"properties": Schema{
+									"key":     Schema{"type": "string"},
+									"message": Schema{"type": "string"},
+								},
+							},
+						},
+					},
+				},
+			},
+			"meta": Schema{"type": "object"},
+			"links": Schema{
+				"type":                 "object",
+				"additionalProperties": Schema{"type": "string", "format": "uri-reference"},
+			},
+		},
+	}
+}
+
+// operation generates the OpenAPI operation of the route.
+func (g *schemaGenerator) operation(route Route) map[string]interface{} {
+	operation := map[string]interface{}{
+		"responses": g.responses(route.Responses),
+	}
+
+	if route.Summary != "" {
+		operation["summary"] = route.Summary
+	}
+
+	if len(route.Tags) > 0 {
+		operation["tags"] = route.Tags
+	}
+
+	if route.Request == nil {
+		return operation
+	}
+
+	t := reflect.TypeOf(route.Request)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return operation
+	}
+
+	if parameters := g.parameters(t); len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	isParameter := func(field reflect.StructField) bool {
+		name, _ := parameterName(field)
+		return name != ""
+	}
+
+	switch strings.ToUpper(route.Method) {
+	case "POST", "PUT", "PATCH", "DELETE":
+		body := g.structSchema(t, isParameter)
+		if len(body["properties"].(Schema)) > 0 {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": body},
+				},
+			}
+		}
+	}
+
+	return operation
+}
+
+// parameters generates the OpenAPI parameters of the request data type,
+// including the parameters of its embedded structs.
+func (g *schemaGenerator) parameters(t reflect.Type) []map[string]interface{} {
+	parameters := make([]map[string]interface{}, 0)
+
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+				collect(field.Type)
+				continue
+			}
+
+			name, in := parameterName(field)
+			if name == "" {
+				continue
+			}
+
+			schema := g.schemaOf(field.Type)
+			rules, required := validationRules(field)
+			if len(rules) > 0 {
+				schema = applyRules(schema, rules)
+			}
+
+			parameters = append(parameters, map[string]interface{}{
+				"name":     name,
+				"in":       in,
+				"required": required || in == "path",
+				"schema":   schema,
+			})
+		}
+	}
+	collect(t)
+
+	return parameters
+}
+
+// responses generates the OpenAPI responses of the route, each having the
+// envelope with the schema of its payload data.
+func (g *schemaGenerator) responses(responses map[http.StatusCode]interface{}) map[string]interface{} {
+	statuses := make([]int, 0, len(responses))
+	for status := range responses {
+		statuses = append(statuses, status.Int())
+	}
+	sort.Ints(statuses)
+
+	generated := make(map[string]interface{}, len(responses))
+	for _, status := range statuses {
+		schema := Schema{"$ref": "#/components/schemas/Envelope"}
+		if data := responses[http.StatusCode(status)]; data != nil {
+			schema = Schema{"allOf": []Schema{
+				schema,
+				{"properties": Schema{"data": g.schemaOf(reflect.TypeOf(data))}},
+			}}
+		}
+
+		generated[strconv.Itoa(status)] = map[string]interface{}{
+			"description": nethttp.StatusText(status),
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schema},
+			},
+		}
+	}
+
+	if len(generated) == 0 {
+		generated["default"] = map[string]interface{}{"description": "Default response"}
+	}
+
+	return generated
+}
@@ -0,0 +1,129 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Kamva/pantopoda/http"
+)
+
+type paging struct {
+	Page    int `query:"page" validate:"gte=1"`
+	PerPage int `query:"per_page" validate:"gt=0,lt=101"`
+}
+
+type createUserRequest struct {
+	paging
+
+	ID      string            `param:"id"`
+	Trace   string            `header:"X-Trace" validate:"omitempty,uuid"`
+	Name    string            `json:"name" validate:"required,gt=2,lt=33"`
+	Tags    []string          `json:"tags" validate:"gte=1,lte=5"`
+	Labels  map[string]string `json:"labels" validate:"gt=0,lt=10"`
+	Age     int               `json:"age" validate:"gt=17,lt=130"`
+	Score   float64           `json:"score" validate:"min=0.5,max=1"`
+	Country string            `json:"country" validate:"len=2"`
+	Email   string            `json:"email" validate:"email"`
+}
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// document generates the document of the registry, decoded from its json
+// encoding.
+func document(t *testing.T, registry *Registry) map[string]interface{} {
+	t.Helper()
+
+	b, err := json.Marshal(registry.Document())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err = json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	return decoded
+}
+
+// lookup returns the value at the path of keys in the decoded document.
+func lookup(t *testing.T, value interface{}, keys ...string) interface{} {
+	t.Helper()
+
+	for _, key := range keys {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			t.Fatalf("expected an object at %s", key)
+		}
+		value = object[key]
+	}
+
+	return value
+}
+
+func assertSchema(t *testing.T, name string, got interface{}, want string) {
+	t.Helper()
+
+	var expected interface{}
+	if err := json.Unmarshal([]byte(want), &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		b, _ := json.Marshal(got)
+		t.Errorf("expected the schema of %s to be %s, got %s", name, want, b)
+	}
+}
+
+func TestDocument(t *testing.T) {
+	registry := NewRegistry("Users", "1.0.0").Add(Route{
+		Method:    "POST",
+		Path:      "/users/{id:string}",
+		Summary:   "Creates the user.",
+		Request:   createUserRequest{},
+		Responses: map[http.StatusCode]interface{}{http.Created: user{}, http.UnprocessableEntity: nil},
+	})
+
+	operation := lookup(t, document(t, registry), "paths", "/users/{id}", "post")
+	if summary := lookup(t, operation, "summary"); summary != "Creates the user." {
+		t.Errorf("unexpected summary %v", summary)
+	}
+
+	parameters := make(map[string]interface{})
+	for _, parameter := range lookup(t, operation, "parameters").([]interface{}) {
+		parameters[lookup(t, parameter, "name").(string)] = parameter
+	}
+
+	assertSchema(t, "id", parameters["id"], `{"name":"id","in":"path","required":true,"schema":{"type":"string"}}`)
+	assertSchema(t, "X-Trace", parameters["X-Trace"], `{"name":"X-Trace","in":"header","required":false,"schema":{"type":"string","format":"uuid"}}`)
+	assertSchema(t, "page", parameters["page"], `{"name":"page","in":"query","required":false,"schema":{"type":"integer","format":"int32","minimum":1}}`)
+	assertSchema(t, "per_page", parameters["per_page"], `{"name":"per_page","in":"query","required":false,"schema":{"type":"integer","format":"int32","minimum":0,"exclusiveMinimum":true,"maximum":101,"exclusiveMaximum":true}}`)
+
+	body := lookup(t, operation, "requestBody", "content", "application/json", "schema")
+	assertSchema(t, "required", lookup(t, body, "required"), `["name"]`)
+
+	properties := lookup(t, body, "properties").(map[string]interface{})
+	if len(properties) != 7 {
+		t.Errorf("expected the params to be excluded from the body, got %v", properties)
+	}
+
+	tests := map[string]string{
+		"name":    `{"type":"string","minLength":3,"maxLength":32}`,
+		"tags":    `{"type":"array","items":{"type":"string"},"minItems":1,"maxItems":5}`,
+		"labels":  `{"type":"object","additionalProperties":{"type":"string"},"minProperties":1,"maxProperties":9}`,
+		"age":     `{"type":"integer","format":"int32","minimum":17,"exclusiveMinimum":true,"maximum":130,"exclusiveMaximum":true}`,
+		"score":   `{"type":"number","format":"double","minimum":0.5,"maximum":1}`,
+		"country": `{"type":"string","minLength":2,"maxLength":2}`,
+		"email":   `{"type":"string","format":"email"}`,
+	}
+	for name, want := range tests {
+		assertSchema(t, name, properties[name], want)
+	}
+
+	created := lookup(t, operation, "responses", "201", "content", "application/json", "schema", "allOf")
+	assertSchema(t, "created", created, `[{"$ref":"#/components/schemas/Envelope"},{"properties":{"data":{"$ref":"#/components/schemas/user"}}}]`)
+}
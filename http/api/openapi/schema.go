@@ -0,0 +1,313 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is an OpenAPI schema object.
+type Schema map[string]interface{}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaGenerator generates schemas of Go types, collecting the named struct
+// types as components.
+type schemaGenerator struct {
+	components map[string]Schema
+
+	// names are the component names of types, and types are the types of
+	// component names, which is nil for the predefined components.
+	names map[reflect.Type]string
+	types map[string]reflect.Type
+}
+
+func newSchemaGenerator(components map[string]Schema) *schemaGenerator {
+	g := &schemaGenerator{
+		components: components,
+		names:      make(map[reflect.Type]string),
+		types:      make(map[string]reflect.Type),
+	}
+	for name := range components {
+		g.types[name] = nil
+	}
+
+	return g
+}
+
+// schemaOf returns the schema of the type. Named struct types are added to
+// the components and referenced.
+func (g *schemaGenerator) schemaOf(t reflect.Type) Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Schema{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return Schema{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return Schema{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return Schema{"type": "number", "format": "double"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "format": "byte"}
+		}
+		return Schema{"type": "array", "items": g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return Schema{"type": "string", "format": "date-time"}
+		}
+
+		if t.Name() == "" {
+			return g.structSchema(t, nil)
+		}
+
+		name, ok := g.names[t]
+		if !ok {
+			name = g.componentName(t)
+			g.names[t], g.types[name] = name, t
+			g.components[name] = Schema{}
+			g.components[name] = g.structSchema(t, nil)
+		}
+
+		return Schema{"$ref": "#/components/schemas/" + name}
+	default:
+		return Schema{}
+	}
+}
+
+// componentName returns the component name of the type, which is its name,
+// qualified by its package name or path if the name is taken by another type.
+func (g *schemaGenerator) componentName(t reflect.Type) string {
+	pkgPath := t.PkgPath()
+	candidates := []string{
+		t.Name(),
+		pkgPath[strings.LastIndex(pkgPath, "/")+1:] + "." + t.Name(),
+		strings.NewReplacer("/", ".", "~", ".").Replace(pkgPath) + "." + t.Name(),
+	}
+
+	for _, name := range candidates {
+		if _, taken := g.types[name]; !taken {
+			return name
+		}
+	}
+
+	name := candidates[len(candidates)-1]
+	for i := 2; ; i++ {
+		if _, taken := g.types[name+strconv.Itoa(i)]; !taken {
+			return name + strconv.Itoa(i)
+		}
+	}
+}
+
+// structSchema returns the object schema of the struct type. Fields for which
+// skip returns true are excluded.
+func (g *schemaGenerator) structSchema(t reflect.Type, skip func(field reflect.StructField) bool) Schema {
+	properties := Schema{}
+	required := make([]string, 0)
+
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+				collect(field.Type)
+				continue
+			}
+
+			if field.PkgPath != "" || (skip != nil && skip(field)) {
+				continue
+			}
+
+			name := jsonName(field)
+			if name == "" {
+				continue
+			}
+
+			schema := g.schemaOf(field.Type)
+			rules, isRequired := validationRules(field)
+			if len(rules) > 0 {
+				schema = applyRules(schema, rules)
+			}
+			if isRequired {
+				required = append(required, name)
+			}
+
+			properties[name] = schema
+		}
+	}
+	collect(t)
+
+	schema := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// jsonName returns the name of the field in json encoding, or empty string if
+// it is ignored.
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
+
+// validationRules returns the rules of the `validate` tag of the field, and
+// whether the field is required.
+func validationRules(field reflect.StructField) (map[string]string, bool) {
+	rules := make(map[string]string)
+	required := false
+
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule == "dive" {
+			break
+		}
+
+		parts := strings.SplitN(rule, "=", 2)
+		if parts[0] == "required" {
+			required = true
+			continue
+		}
+
+		if parts[0] != "" {
+			rules[parts[0]] = ""
+			if len(parts) == 2 {
+				rules[parts[0]] = parts[1]
+			}
+		}
+	}
+
+	return rules, required
+}
+
+// applyRules adds the constraints of the validation rules to the schema of
+// the field type.
+func applyRules(schema Schema, rules map[string]string) Schema {
+	if _, ok := schema["$ref"]; ok {
+		return schema
+	}
+
+	constrained := Schema{}
+	for key, value := range schema {
+		constrained[key] = value
+	}
+
+	lengthKeywords := map[string][2]string{
+		"string": {"minLength", "maxLength"},
+		"array":  {"minItems", "maxItems"},
+		"object": {"minProperties", "maxProperties"},
+	}
+
+	schemaType, _ := schema["type"].(string)
+	keywords, hasLength := lengthKeywords[schemaType]
+
+	for rule, param := range rules {
+		switch rule {
+		case "min", "max", "len", "gte", "lte", "gt", "lt":
+			if hasLength {
+				applyLength(constrained, keywords, rule, param)
+				continue
+			}
+
+			switch rule {
+			case "min", "gte":
+				constrained["minimum"] = number(param)
+			case "max", "lte":
+				constrained["maximum"] = number(param)
+			case "gt":
+				constrained["minimum"] = number(param)
+				constrained["exclusiveMinimum"] = true
+			case "lt":
+				constrained["maximum"] = number(param)
+				constrained["exclusiveMaximum"] = true
+			}
+		case "oneof":
+			enum := make([]interface{}, 0)
+			for _, value := range strings.Fields(param) {
+				enum = append(enum, value)
+			}
+			constrained["enum"] = enum
+		case "email", "uuid", "ipv4", "ipv6":
+			constrained["format"] = rule
+		case "url", "uri":
+			constrained["format"] = "uri"
+		}
+	}
+
+	return constrained
+}
+
+// applyLength adds the length constraint of the rule to the schema, using the
+// minimum and maximum keywords of its type. The exclusive bounds of gt and lt
+// are converted into inclusive lengths.
+func applyLength(schema Schema, keywords [2]string, rule string, param string) {
+	length, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return
+	}
+
+	switch rule {
+	case "min", "gte":
+		schema[keywords[0]] = length
+	case "max", "lte":
+		schema[keywords[1]] = length
+	case "len":
+		schema[keywords[0]], schema[keywords[1]] = length, length
+	case "gt":
+		schema[keywords[0]] = length + 1
+	case "lt":
+		if length > 0 {
+			schema[keywords[1]] = length - 1
+		}
+	}
+}
+
+// number converts the rule parameter into a number.
+func number(param string) interface{} {
+	if i, err := strconv.ParseInt(param, 10, 64); err == nil {
+		return i
+	}
+
+	if f, err := strconv.ParseFloat(param, 64); err == nil {
+		return f
+	}
+
+	return param
+}
+
+// parameterName returns the name and location of the field if it is a
+// request parameter, according to the tags used by api.Bind.
+func parameterName(field reflect.StructField) (string, string) {
+	for _, in := range []string{"param", "query", "header"} {
+		name := strings.Split(field.Tag.Get(in), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		if in == "param" {
+			return name, "path"
+		}
+
+		return name, in
+	}
+
+	return "", ""
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"fmt"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Kamva/pantopoda/http"
+)
+
+// Query params of paginated requests.
+const (
+	PageParam    = "page"
+	PerPageParam = "per_page"
+	CursorParam  = "cursor"
+)
+
+// Page is the position of a page in page number pagination.
+type Page struct {
+	// Number is the page number, starting from 1.
+	Number int
+
+	// PerPage is the number of items in each page.
+	PerPage int
+
+	// Total is the total number of items.
+	Total int64
+}
+
+// ParsePage reads the page number and the page size of the request from the
+// `page` and `per_page` query params. The page size is perPage if it is not
+// given, and it is limited to maxPerPage.
+func ParsePage(r *nethttp.Request, perPage int, maxPerPage int) Page {
+	query := r.URL.Query()
+
+	page := Page{Number: 1, PerPage: perPage}
+	if number, err := strconv.Atoi(query.Get(PageParam)); err == nil && number > 0 {
+		page.Number = number
+	}
+
+	page.PerPage = parsePerPage(query.Get(PerPageParam), perPage, maxPerPage)
+
+	return page
+}
+
+// Offset returns the number of items before the page.
+func (p Page) Offset() int {
+	return (p.Number - 1) * p.PerPage
+}
+
+// TotalPages returns the number of pages.
+func (p Page) TotalPages() int {
+	if p.PerPage <= 0 {
+		return 0
+	}
+
+	return int((p.Total + int64(p.PerPage) - 1) / int64(p.PerPage))
+}
+
+// CursorPage is the position of a page in cursor pagination.
+type CursorPage struct {
+	// Cursor is the cursor of the requested page, empty for the first page.
+	Cursor string
+
+	// PerPage is the number of items in each page.
+	PerPage int
+
+	// Next is the cursor of the next page, empty if it is the last page.
+	Next string
+
+	// Previous is the cursor of the previous page, empty if it is the first
+	// page.
+	Previous string
+}
+
+// ParseCursorPage reads the cursor and the page size of the request from the
+// `cursor` and `per_page` query params. The page size is perPage if it is not
+// given, and it is limited to maxPerPage.
+func ParseCursorPage(r *nethttp.Request, perPage int, maxPerPage int) CursorPage {
+	query := r.URL.Query()
+
+	return CursorPage{
+		Cursor:  query.Get(CursorParam),
+		PerPage: parsePerPage(query.Get(PerPageParam), perPage, maxPerPage),
+	}
+}
+
+func parsePerPage(value string, perPage int, maxPerPage int) int {
+	if n, err := strconv.Atoi(value); err == nil && n > 0 {
+		perPage = n
+	}
+
+	if maxPerPage > 0 && perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	return perPage
+}
+
+// Paginated generate a Response with status code 200 for a page of items in
+// page number pagination. The page is responded in `data`, along with the
+// `page`, `per_page`, `total` and `total_pages` in `meta`. The `first`,
+// `prev`, `next` and `last` page links are responded in `links` and the Link
+// header.
+func (r Response) Paginated(code string, data interface{}, page Page, header ...ResponseHeader) {
+	totalPages := page.TotalPages()
+	meta := map[string]interface{}{
+		"page":        page.Number,
+		"per_page":    page.PerPage,
+		"total":       page.Total,
+		"total_pages": totalPages,
+	}
+
+	pageLink := func(number int) map[string]string {
+		return map[string]string{PageParam: strconv.Itoa(number), PerPageParam: strconv.Itoa(page.PerPage)}
+	}
+
+	links := make([]link, 0, 4)
+	links = append(links, link{"first", pageLink(1)})
+	if page.Number > 1 {
+		links = append(links, link{"prev", pageLink(page.Number - 1)})
+	}
+	if page.Number < totalPages {
+		links = append(links, link{"next", pageLink(page.Number + 1)})
+	}
+	if totalPages > 0 {
+		links = append(links, link{"last", pageLink(totalPages)})
+	}
+
+	r.paginated(code, data, meta, links, header)
+}
+
+// CursorPaginated generate a Response with status code 200 for a page of
+// items in cursor pagination. The page is responded in `data`, along with the
+// `per_page`, `next_cursor` and `prev_cursor` in `meta`. The `prev` and
+// `next` page links are responded in `links` and the Link header.
+func (r Response) CursorPaginated(code string, data interface{}, page CursorPage, header ...ResponseHeader) {
+	meta := map[string]interface{}{"per_page": page.PerPage}
+	links := make([]link, 0, 2)
+
+	if page.Previous != "" {
+		meta["prev_cursor"] = page.Previous
+		links = append(links, link{"prev", map[string]string{CursorParam: page.Previous, PerPageParam: strconv.Itoa(page.PerPage)}})
+	}
+	if page.Next != "" {
+		meta["next_cursor"] = page.Next
+		links = append(links, link{"next", map[string]string{CursorParam: page.Next, PerPageParam: strconv.Itoa(page.PerPage)}})
+	}
+
+	r.paginated(code, data, meta, links, header)
+}
+
+// link is a link to another page of the paginated request.
+type link struct {
+	rel   string
+	query map[string]string
+}
+
+// paginated responds the page of items. Unlike other responses, `data` is
+// responded even if it is empty, so that clients always find the items.
+func (r Response) paginated(code string, data interface{}, meta map[string]interface{}, links []link, headers []ResponseHeader) {
+	payload := Payload{Data: data, Meta: meta, EmitEmpty: []string{"data"}}
+	if data == nil {
+		payload.Data = []interface{}{}
+	}
+
+	if request := r.w.Request(); request != nil && len(links) > 0 {
+		payload.Links = make(map[string]string, len(links))
+		values := make([]string, 0, len(links))
+		for _, l := range links {
+			u := *request.URL
+			query := u.Query()
+			for key, value := range l.query {
+				query.Set(key, value)
+			}
+			u.RawQuery = query.Encode()
+
+			payload.Links[l.rel] = u.String()
+			values = append(values, fmt.Sprintf(`<%s>; rel="%s"`, u.String(), l.rel))
+		}
+
+		headers = append(headers, ResponseHeader{"Link": strings.Join(values, ", ")})
+	}
+
+	r.Response(code, http.OK, payload, headers...)
+}
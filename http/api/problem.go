@@ -0,0 +1,103 @@
+package api
+
+import (
+	nethttp "net/http"
+	"strings"
+
+	"github.com/Kamva/pantopoda/http"
+)
+
+// ErrorFormat determines how error responses are rendered.
+type ErrorFormat int
+
+const (
+	// DefaultFormat uses the globally selected error format.
+	DefaultFormat ErrorFormat = iota
+
+	// EnvelopeFormat renders error responses in the `code`, `message`, `data`
+	// envelope, like any other response.
+	EnvelopeFormat
+
+	// ProblemFormat renders error responses as RFC 7807 problem details.
+	ProblemFormat
+)
+
+// errorFormat is the globally selected error format.
+var errorFormat = EnvelopeFormat
+
+// SetErrorFormat selects the format used for error responses globally.
+func SetErrorFormat(format ErrorFormat) {
+	if format == DefaultFormat {
+		format = EnvelopeFormat
+	}
+
+	errorFormat = format
+}
+
+// ProblemTypeBaseURI is prepended to the response code to generate the `type`
+// member of problem details. When it is empty, the `type` is `about:blank`.
+var ProblemTypeBaseURI = ""
+
+// problemContentTypes maps the content types of encoders into their problem
+// details counterpart.
+var problemContentTypes = map[string]string{
+	"application/json": "application/problem+json",
+	"application/xml":  "application/problem+xml",
+}
+
+// WithErrorFormat returns a copy of the Response which renders error
+// responses in given format, regardless of the global error format.
+func (r Response) WithErrorFormat(format ErrorFormat) Response {
+	r.format = format
+	return r
+}
+
+// isProblem checks that the response with given status should be rendered as
+// problem details.
+func (r Response) isProblem(status http.StatusCode) bool {
+	format := r.format
+	if format == DefaultFormat {
+		format = errorFormat
+	}
+
+	return format == ProblemFormat && status >= 400
+}
+
+// problemBody generates the problem details of the response. The envelope
+// fields other than `message` are kept as extension members.
+func (r Response) problemBody(status http.StatusCode, body responseJSON) responseJSON {
+	problem := make(responseJSON, len(body)+5)
+	for key, value := range body {
+		problem[key] = value
+	}
+	delete(problem, "message")
+
+	problem["type"] = "about:blank"
+	if code, _ := body["code"].(string); ProblemTypeBaseURI != "" && code != "" {
+		problem["type"] = ProblemTypeBaseURI + code
+	}
+
+	problem["title"] = nethttp.StatusText(status.Int())
+	problem["status"] = status.Int()
+
+	if message, ok := body["message"]; ok {
+		problem["detail"] = message
+	}
+
+	if request := r.w.Request(); request != nil {
+		problem["instance"] = request.URL.RequestURI()
+	}
+
+	return problem
+}
+
+// problemContentType converts the content type of an encoder into its
+// problem details counterpart, if there is any.
+func problemContentType(contentType string) string {
+	parts := strings.SplitN(contentType, ";", 2)
+	if problemType, ok := problemContentTypes[parts[0]]; ok {
+		parts[0] = problemType
+	}
+
+	return strings.Join(parts, ";")
+}
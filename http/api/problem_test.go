@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	nethttp "net/http"
+	"testing"
+
+	"github.com/Kamva/pantopoda/http"
+	"github.com/Kamva/pantopoda/http/api/apitest"
+)
+
+// serveProblem responds with given status and the error format, for a request
+// with the Accept header.
+func serveProblem(format ErrorFormat, status http.StatusCode, accept string) apitest.Result {
+	handler := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		NewHTTPResponse(w, r).WithErrorFormat(format).Response("user_not_found", status, Payload{
+			Message: "no such user.",
+			Meta:    map[string]interface{}{"id": 1},
+		})
+	})
+
+	req := apitest.NewRequest("GET", "/users/1?full=true", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	return apitest.Serve(handler, req)
+}
+
+func TestProblemDetails(t *testing.T) {
+	defer func(base string) { ProblemTypeBaseURI = base }(ProblemTypeBaseURI)
+	ProblemTypeBaseURI = "https://example.com/problems/"
+
+	result := serveProblem(ProblemFormat, http.NotFound, "")
+	result.AssertStatus(t, http.NotFound)
+	result.AssertHeader(t, "Content-Type", "application/problem+json; charset=UTF-8")
+
+	var problem map[string]interface{}
+	if err := json.Unmarshal(result.Body, &problem); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"type":     "https://example.com/problems/user_not_found",
+		"title":    "Not Found",
+		"status":   float64(404),
+		"detail":   "no such user.",
+		"instance": "/users/1?full=true",
+		"code":     "user_not_found",
+	}
+	for key, value := range expected {
+		if problem[key] != value {
+			t.Errorf("expected %s to be %v, got %v", key, value, problem[key])
+		}
+	}
+	if _, ok := problem["message"]; ok {
+		t.Error("expected the message to be rendered as detail")
+	}
+	if meta, ok := problem["meta"].(map[string]interface{}); !ok || meta["id"] != float64(1) {
+		t.Errorf("expected the meta to be kept as an extension member, got %v", problem["meta"])
+	}
+
+	ProblemTypeBaseURI = ""
+	if result := serveProblem(ProblemFormat, http.NotFound, ""); !jsonContains(result.Body, "type", "about:blank") {
+		t.Errorf("expected the blank problem type, got %s", result.Body)
+	}
+}
+
+func TestProblemDetailsFormat(t *testing.T) {
+	defer SetErrorFormat(EnvelopeFormat)
+
+	tests := []struct {
+		global      ErrorFormat
+		format      ErrorFormat
+		status      http.StatusCode
+		accept      string
+		contentType string
+	}{
+		{EnvelopeFormat, DefaultFormat, http.NotFound, "", "application/json; charset=UTF-8"},
+		{EnvelopeFormat, ProblemFormat, http.NotFound, "", "application/problem+json; charset=UTF-8"},
+		{ProblemFormat, DefaultFormat, http.NotFound, "", "application/problem+json; charset=UTF-8"},
+		{ProblemFormat, EnvelopeFormat, http.NotFound, "", "application/json; charset=UTF-8"},
+		{ProblemFormat, DefaultFormat, http.OK, "", "application/json; charset=UTF-8"},
+		{ProblemFormat, DefaultFormat, http.Conflict, "application/xml", "application/problem+xml; charset=UTF-8"},
+		{ProblemFormat, DefaultFormat, http.Conflict, "application/x-msgpack", "application/x-msgpack"},
+	}
+
+	for _, test := range tests {
+		SetErrorFormat(test.global)
+		result := serveProblem(test.format, test.status, test.accept)
+		result.AssertStatus(t, test.status)
+		if !result.AssertHeader(t, "Content-Type", test.contentType) {
+			t.Logf("case %+v", test)
+		}
+	}
+}
+
+// jsonContains checks that the json object has the string member.
+func jsonContains(body []byte, key string, value string) bool {
+	var object map[string]interface{}
+	return json.Unmarshal(body, &object) == nil && object[key] == value
+}
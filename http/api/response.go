@@ -1,7 +1,8 @@
 package api
 
 import (
-	"github.com/Kamva/nautilus"
+	nethttp "net/http"
+
 	"github.com/Kamva/pantopoda/http"
 	"github.com/kataras/iris"
 	"github.com/mitchellh/mapstructure"
@@ -12,8 +13,20 @@ type responseJSON map[string]interface{}
 
 // Payload is placeholder for API Response body
 type Payload struct {
-	Message string      `json:"message" mapstructure:"message"`
-	Data    interface{} `json:"data" mapstructure:"data"`
+	Message string            `json:"message" mapstructure:"message"`
+	Data    interface{}       `json:"data" mapstructure:"data"`
+	Errors  interface{}       `json:"errors" mapstructure:"errors"`
+	Meta    interface{}       `json:"meta" mapstructure:"meta"`
+	Links   map[string]string `json:"links" mapstructure:"links"`
+
+	// Extra contains additional top-level fields of the response body. It
+	// cannot override the fields above.
+	Extra map[string]interface{} `json:"-" mapstructure:"-"`
+
+	// EmitEmpty lists the fields which are responded even if they are empty,
+	// e.g. "data" to respond `"data": []` or `"data": 0` instead of dropping
+	// the field.
+	EmitEmpty []string `json:"-" mapstructure:"-"`
 }
 
 // Map convert payload data to map
@@ -30,17 +43,40 @@ func (p Payload) Map() map[string]interface{} {
 	return output
 }
 
+// emits checks that the field is responded even if it is empty.
+func (p Payload) emits(field string) bool {
+	for _, f := range p.EmitEmpty {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
 // ResponseHeader is map of API Response headers
 type ResponseHeader map[string]string
 
 // Response is an object responsible for generating api Response
 type Response struct {
-	ctx iris.Context
+	w      Writer
+	format ErrorFormat
 }
 
 // NewResponse instantiate a new Response object for given ctx
 func NewResponse(ctx iris.Context) Response {
-	return Response{ctx: ctx}
+	return NewWriterResponse(NewIrisWriter(ctx))
+}
+
+// NewHTTPResponse instantiate a new Response object for given net/http
+// response writer and request.
+func NewHTTPResponse(w nethttp.ResponseWriter, r *nethttp.Request) Response {
+	return NewWriterResponse(NewHTTPWriter(w, r))
+}
+
+// NewWriterResponse instantiate a new Response object for given writer
+func NewWriterResponse(w Writer) Response {
+	return Response{w: w}
 }
 
 // Continue generate a Response with status code 100.
@@ -1378,8 +1414,15 @@ func (r Response) NetworkConnectTimeoutError(code string, payload Payload, heade
 	r.Response(code, http.NetworkConnectTimeoutError, payload, header...)
 }
 
-// Response generate the response from given data
+// Response generate the response from given data. The body is encoded with
+// the encoder negotiated from the Accept header of the request, and responds
+// with status code 406 if none of the accepted media types are supported.
 func (r Response) Response(code string, status http.StatusCode, payload Payload, headers ...ResponseHeader) {
+	r.write(code, status, payloadEnvelope{code: code, payload: payload}, mergeHeaders(headers))
+}
+
+// mergeHeaders merges the response headers into one.
+func mergeHeaders(headers []ResponseHeader) ResponseHeader {
 	responseHeader := ResponseHeader{}
 	for _, header := range headers {
 		for key, value := range header {
@@ -1387,21 +1430,105 @@ func (r Response) Response(code string, status http.StatusCode, payload Payload,
 		}
 	}
 
-	body := make(responseJSON)
-	body["code"] = code
+	return responseHeader
+}
+
+// write encodes the body with the encoder negotiated for the request and
+// writes it along with the status and headers.
+func (r Response) write(code string, status http.StatusCode, body responseBody, headers ResponseHeader) {
+	request := r.w.Request()
 
-	payloadMap := payload.Map()
-	for key, value := range payloadMap {
-		if !nautilus.Empty(value) {
-			body[key] = value
+	accept := ""
+	if request != nil {
+		accept = request.Header.Get("Accept")
+	}
+
+	mediaType, encoder, ok := negotiate(accept)
+	if !ok {
+		mediaType, encoder = defaultEncoder()
+		status = http.NotAcceptable
+		body = responseJSON{
+			"code":    NotAcceptableCode,
+			"message": "none of the accepted media types are supported.",
 		}
 	}
 
-	r.ctx.StatusCode(status.Int())
+	body = body.withFields(fieldValues(request))
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err := encoder.Encode(buf, r.body(status, body), wantsPretty(request)); err != nil {
+		logger.Printf("error in encoding response payload of %q: %s", code, err)
+
+		buf.Reset()
+		status = http.InternalServerError
+		_ = encoder.Encode(buf, r.body(status, responseJSON{
+			"code":    code,
+			"message": "error in encoding response payload.",
+		}), false)
+	}
+
+	for key, value := range headers {
+		r.w.Header().Set(key, value)
+	}
+	if r.isProblem(status) {
+		r.w.Header().Set("Content-Type", problemContentType(contentType(mediaType, encoder)))
+	} else {
+		r.w.Header().Set("Content-Type", contentType(mediaType, encoder))
+	}
+	r.w.Header().Add("Vary", "Accept")
+
+	if rec := recorderOf(request); rec != nil {
+		rec.record(status.Int(), r.w.Header(), buf.Bytes())
+	}
+
+	if compressed := r.compress(status, buf); compressed != nil {
+		defer putBuffer(compressed)
+		buf = compressed
+	}
+
+	r.w.WriteHeader(status.Int())
+	_, _ = r.w.Write(buf.Bytes())
+}
 
-	for key, value := range responseHeader {
-		r.ctx.Header(key, value)
+// body returns the body of the response with given status, which is either
+// the envelope or its problem details.
+func (r Response) body(status http.StatusCode, body responseBody) interface{} {
+	if r.isProblem(status) {
+		return r.problemBody(status, body.envelope())
 	}
 
-	_, _ = r.ctx.JSON(body)
+	return body.value()
+}
+
+// responseBody is the body of a response before encoding.
+type responseBody interface {
+	// withFields returns the body along with the top-level fields which are
+	// not present in the body.
+	withFields(fields map[string]interface{}) responseBody
+
+	// envelope returns the fields of the body.
+	envelope() responseJSON
+
+	// value returns the value passed to the encoder.
+	value() interface{}
+}
+
+func (body responseJSON) withFields(fields map[string]interface{}) responseBody {
+	for key, value := range fields {
+		if _, ok := body[key]; !ok {
+			body[key] = value
+		}
+	}
+
+	return body
+}
+
+func (body responseJSON) envelope() responseJSON {
+	return body
+}
+
+func (body responseJSON) value() interface{} {
+	return body
 }
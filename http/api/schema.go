@@ -0,0 +1,47 @@
+package api
+
+import (
+	nethttp "net/http"
+
+	"github.com/Kamva/pantopoda"
+	"github.com/Kamva/pantopoda/schema"
+	"github.com/kataras/iris"
+)
+
+// SchemaValidator returns an iris middleware which validates the request
+// body against the JSON Schema. When validation fails, it responds with the
+// corresponding error Response, otherwise the next handler is executed with
+// the request body intact. Bodies larger than MaxRequestBodySize are
+// responded with status code 413.
+func SchemaValidator(s *schema.Schema) iris.Handler {
+	return func(ctx iris.Context) {
+		if validateSchema(NewIrisWriter(ctx), s) {
+			ctx.Next()
+		}
+	}
+}
+
+// SchemaValidatorHTTP is the net/http counterpart of SchemaValidator.
+func SchemaValidatorHTTP(s *schema.Schema) func(next nethttp.Handler) nethttp.Handler {
+	return func(next nethttp.Handler) nethttp.Handler {
+		return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			if validateSchema(NewHTTPWriter(w, r), s) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func validateSchema(w Writer, s *schema.Schema) bool {
+	body, ok := readBody(w)
+	if !ok {
+		return false
+	}
+
+	if validationError := pantopoda.ValidateSchema(s, body); validationError.Failed() {
+		NewWriterResponse(w).Error(string(validationError.ErrorType), validationError)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"io/ioutil"
+	nethttp "net/http"
+	"strings"
+	"testing"
+
+	"github.com/Kamva/pantopoda/http"
+	"github.com/Kamva/pantopoda/http/api/apitest"
+	"github.com/Kamva/pantopoda/schema"
+)
+
+// echoHandler responds the request body it receives.
+var echoHandler = nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	w.Write(body)
+})
+
+func TestSchemaValidatorHTTP(t *testing.T) {
+	s := schema.MustParse([]byte(`{"type":"object","required":["name"],"properties":{"name":{"type":"string","minLength":2}}}`))
+	handler := SchemaValidatorHTTP(s)(echoHandler)
+
+	tests := []struct {
+		body   string
+		status http.StatusCode
+	}{
+		{`{"name":"ab"}`, http.OK},
+		{`{"name":"a"}`, http.UnprocessableEntity},
+		{`{}`, http.UnprocessableEntity},
+		{`{"name":`, http.BadRequest},
+		{`{"name":"ab"} {}`, http.BadRequest},
+	}
+
+	for _, test := range tests {
+		req := apitest.NewRequest("POST", "/users", nil)
+		req.Body = ioutil.NopCloser(strings.NewReader(test.body))
+
+		result := apitest.Serve(handler, req)
+		result.AssertStatus(t, test.status)
+		if test.status == http.OK && string(result.Body) != test.body {
+			t.Errorf("expected the body to reach the next handler intact, got %s", result.Body)
+		}
+	}
+}
+
+func TestSchemaValidatorHTTPSizeLimit(t *testing.T) {
+	defer func(limit int64) { MaxRequestBodySize = limit }(MaxRequestBodySize)
+	MaxRequestBodySize = 16
+
+	handler := SchemaValidatorHTTP(schema.MustParse([]byte(`true`)))(echoHandler)
+
+	req := apitest.NewRequest("POST", "/users", nil)
+	req.Body = ioutil.NopCloser(strings.NewReader(`{"name":"` + strings.Repeat("a", 32) + `"}`))
+	result := apitest.Serve(handler, req)
+	result.AssertStatus(t, http.PayloadTooLarge)
+	result.AssertCode(t, PayloadTooLargeCode)
+
+	req = apitest.NewRequest("POST", "/users", nil)
+	req.Body = ioutil.NopCloser(strings.NewReader(`{"name":"a"}`))
+	apitest.Serve(handler, req).AssertStatus(t, http.OK)
+}
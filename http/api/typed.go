@@ -0,0 +1,95 @@
+package api
+
+import (
+	"github.com/Kamva/pantopoda/http"
+)
+
+// TypedPayload is the API Response body with data of type T. Unlike Payload,
+// it is encoded directly using the tags of T, without converting it into a
+// map, and `data` is always responded, even if it is the zero value of T.
+type TypedPayload[T any] struct {
+	Message string
+	Data    T
+	Errors  interface{}
+	Meta    interface{}
+	Links   map[string]string
+}
+
+// typedBody is the envelope of typed payloads as encoded.
+type typedBody[T any] struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message,omitempty"`
+	Data    T                 `json:"data"`
+	Errors  interface{}       `json:"errors,omitempty"`
+	Meta    interface{}       `json:"meta,omitempty"`
+	Links   map[string]string `json:"links,omitempty"`
+}
+
+// typedEnvelope is the response body of typed payloads.
+type typedEnvelope[T any] struct {
+	body   typedBody[T]
+	fields map[string]interface{}
+}
+
+func (e typedEnvelope[T]) withFields(fields map[string]interface{}) responseBody {
+	e.fields = fields
+	return e
+}
+
+func (e typedEnvelope[T]) envelope() responseJSON {
+	body := responseJSON{"code": e.body.Code, "data": e.body.Data}
+	if e.body.Message != "" {
+		body["message"] = e.body.Message
+	}
+	if e.body.Errors != nil {
+		body["errors"] = e.body.Errors
+	}
+	if e.body.Meta != nil {
+		body["meta"] = e.body.Meta
+	}
+	if len(e.body.Links) > 0 {
+		body["links"] = e.body.Links
+	}
+
+	for key, value := range e.fields {
+		if _, ok := body[key]; !ok {
+			body[key] = value
+		}
+	}
+
+	return body
+}
+
+// value returns the typed envelope as is, unless there are top-level fields
+// to be added to it.
+func (e typedEnvelope[T]) value() interface{} {
+	if len(e.fields) > 0 {
+		return e.envelope()
+	}
+
+	return e.body
+}
+
+// Respond generate a Response with given status code and typed payload.
+func Respond[T any](r Response, code string, status http.StatusCode, payload TypedPayload[T], header ...ResponseHeader) {
+	body := typedEnvelope[T]{body: typedBody[T]{
+		Code:    code,
+		Message: payload.Message,
+		Data:    payload.Data,
+		Errors:  payload.Errors,
+		Meta:    payload.Meta,
+		Links:   payload.Links,
+	}}
+
+	r.write(code, status, body, mergeHeaders(header))
+}
+
+// RespondOK generate a Response with status code 200 and typed payload.
+func RespondOK[T any](r Response, code string, payload TypedPayload[T], header ...ResponseHeader) {
+	Respond(r, code, http.OK, payload, header...)
+}
+
+// RespondCreated generate a Response with status code 201 and typed payload.
+func RespondCreated[T any](r Response, code string, payload TypedPayload[T], header ...ResponseHeader) {
+	Respond(r, code, http.Created, payload, header...)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	nethttp "net/http"
+	"testing"
+
+	"github.com/Kamva/pantopoda/http"
+	"github.com/Kamva/pantopoda/http/api/apitest"
+)
+
+type typedUser struct {
+	ID    int    `json:"id"`
+	Name  string `json:"user_name"`
+	Email string `json:"email,omitempty"`
+}
+
+// serveTyped serves the responder, and returns the result.
+func serveTyped(respond func(r Response)) apitest.Result {
+	handler := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		respond(NewHTTPResponse(w, r))
+	})
+
+	return apitest.Serve(handler, apitest.NewRequest("GET", "/users/1", nil))
+}
+
+func TestRespond(t *testing.T) {
+	tests := []struct {
+		respond func(r Response)
+		status  http.StatusCode
+		want    string
+	}{
+		{
+			func(r Response) {
+				RespondOK(r, "user_fetched", TypedPayload[typedUser]{Data: typedUser{ID: 1, Name: "alice"}})
+			},
+			http.OK,
+			`{"code":"user_fetched","data":{"id":1,"user_name":"alice"}}`,
+		},
+		{
+			func(r Response) {
+				RespondOK(r, "count", TypedPayload[int]{})
+			},
+			http.OK,
+			`{"code":"count","data":0}`,
+		},
+		{
+			func(r Response) {
+				RespondCreated(r, "user_created", TypedPayload[*typedUser]{
+					Message: "created.",
+					Data:    &typedUser{ID: 2},
+					Meta:    map[string]int{"version": 1},
+					Links:   map[string]string{"self": "/users/2"},
+				}, ResponseHeader{"Location": "/users/2"})
+			},
+			http.Created,
+			`{"code":"user_created","message":"created.","data":{"id":2,"user_name":""},"meta":{"version":1},"links":{"self":"/users/2"}}`,
+		},
+		{
+			func(r Response) {
+				Respond(r, "users_invalid", http.UnprocessableEntity, TypedPayload[[]typedUser]{Errors: []string{"invalid"}})
+			},
+			http.UnprocessableEntity,
+			`{"code":"users_invalid","data":null,"errors":["invalid"]}`,
+		},
+	}
+
+	for _, test := range tests {
+		result := serveTyped(test.respond)
+		result.AssertStatus(t, test.status)
+		if got := string(result.Body); got != test.want+"\n" {
+			t.Errorf("expected the body %s, got %s", test.want, got)
+		}
+	}
+
+	serveTyped(tests[2].respond).AssertHeader(t, "Location", "/users/2")
+}
+
+func TestRespondFields(t *testing.T) {
+	defer func(registered []registeredField) { fields = registered }(fields)
+
+	RegisterField("version", func(r *nethttp.Request) interface{} {
+		return 1
+	})
+	RegisterField("data", func(r *nethttp.Request) interface{} {
+		return "overridden"
+	})
+
+	result := serveTyped(func(r Response) {
+		RespondOK(r, "user_fetched", TypedPayload[typedUser]{Data: typedUser{ID: 1}})
+	})
+
+	want := `{"code":"user_fetched","data":{"id":1,"user_name":""},"version":1}` + "\n"
+	if got := string(result.Body); got != want {
+		t.Errorf("expected the registered fields to be added, got %s", got)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/kataras/iris"
+)
+
+// Writer is the interface used by Response to write the HTTP response. It is
+// an http.ResponseWriter which also exposes the request being responded.
+type Writer interface {
+	http.ResponseWriter
+
+	// Request returns the incoming request that is being responded.
+	Request() *http.Request
+}
+
+// httpWriter is the Writer adapter for net/http compatible stacks.
+type httpWriter struct {
+	http.ResponseWriter
+	request *http.Request
+}
+
+// NewHTTPWriter wraps the net/http response writer and request into a Writer.
+func NewHTTPWriter(w http.ResponseWriter, r *http.Request) Writer {
+	return httpWriter{ResponseWriter: w, request: r}
+}
+
+func (w httpWriter) Request() *http.Request {
+	return w.request
+}
+
+// irisWriter is the Writer adapter for iris context.
+type irisWriter struct {
+	ctx iris.Context
+}
+
+// NewIrisWriter wraps the iris context into a Writer.
+func NewIrisWriter(ctx iris.Context) Writer {
+	return irisWriter{ctx: ctx}
+}
+
+func (w irisWriter) Header() http.Header {
+	return w.ctx.ResponseWriter().Header()
+}
+
+func (w irisWriter) WriteHeader(status int) {
+	w.ctx.StatusCode(status)
+}
+
+func (w irisWriter) Write(b []byte) (int, error) {
+	return w.ctx.Write(b)
+}
+
+func (w irisWriter) Request() *http.Request {
+	return w.ctx.Request()
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Kamva/pantopoda/http"
+)
+
+// detachedWriter is a Writer which is not bound to any incoming request,
+// e.g. for responding over a message queue.
+type detachedWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (detachedWriter) Request() *nethttp.Request {
+	return nil
+}
+
+func TestNewWriterResponse(t *testing.T) {
+	w := detachedWriter{httptest.NewRecorder()}
+	NewWriterResponse(w).Created("user_created", Payload{Message: "created."}, ResponseHeader{"Location": "/users/1"})
+
+	if w.Code != http.Created.Int() {
+		t.Errorf("expected status %d, got %d", http.Created, w.Code)
+	}
+	if got := w.Header().Get("Location"); got != "/users/1" {
+		t.Errorf("expected the response header to be set, got %q", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json; charset=UTF-8" {
+		t.Errorf("expected the default encoder without a request, got %q", got)
+	}
+	if got := w.Body.String(); got != "{\"code\":\"user_created\",\"message\":\"created.\"}\n" {
+		t.Errorf("unexpected body %s", got)
+	}
+}
+
+func TestNewHTTPWriter(t *testing.T) {
+	req := httptest.NewRequest("GET", "/users/1", nil)
+	w := NewHTTPWriter(httptest.NewRecorder(), req)
+
+	if w.Request() != req {
+		t.Error("expected the writer to expose the request")
+	}
+}
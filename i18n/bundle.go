@@ -0,0 +1,219 @@
+// Package i18n provides translation catalogs for validation error messages.
+package i18n
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Kamva/pantopoda"
+)
+
+// Catalog is a map of translation keys to message templates of a locale. The
+// templates can contain `{field}`, `{param}` and `{rule}` placeholders, which
+// are replaced by the field label, the rule parameter and the rule name.
+//
+// Field labels are looked up using `fields.<field path>` keys, and default to
+// the field path itself.
+type Catalog map[string]string
+
+// Bundle is a set of catalogs for different locales, along with the fallback
+// chain of each locale.
+type Bundle struct {
+	mu            sync.RWMutex
+	catalogs      map[string]Catalog
+	fallbacks     map[string][]string
+	defaultLocale string
+}
+
+// NewBundle generate new bundle with given default locale, which is used
+// when none of the requested locales have the message.
+func NewBundle(defaultLocale string) *Bundle {
+	return &Bundle{
+		catalogs:      make(map[string]Catalog),
+		fallbacks:     make(map[string][]string),
+		defaultLocale: normalize(defaultLocale),
+	}
+}
+
+// Add adds the messages of catalog to the catalog of locale, replacing the
+// existing messages with the same key.
+func (b *Bundle) Add(locale string, catalog Catalog) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	locale = normalize(locale)
+	if b.catalogs[locale] == nil {
+		b.catalogs[locale] = make(Catalog, len(catalog))
+	}
+
+	for key, message := range catalog {
+		b.catalogs[locale][key] = message
+	}
+}
+
+// SetFallback sets the locales to be tried, in order, when a message does not
+// exist in the catalog of locale.
+func (b *Bundle) SetFallback(locale string, fallbacks ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	normalized := make([]string, len(fallbacks))
+	for i, fallback := range fallbacks {
+		normalized[i] = normalize(fallback)
+	}
+
+	b.fallbacks[normalize(locale)] = normalized
+}
+
+// Message returns the message of the key in the first locale of the fallback
+// chain having it, with the placeholders replaced by params. It returns false
+// if none of the locales have the message.
+func (b *Bundle) Message(locales []string, key string, params map[string]string) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, locale := range b.chain(locales) {
+		if message, ok := b.catalogs[locale][key]; ok {
+			return interpolate(message, params), true
+		}
+	}
+
+	return "", false
+}
+
+// Translate returns the message of the validation error in the first locale
+// of the fallback chain having it. The message is looked up using the
+// translation key of the error, then using its rule and then using the
+// `invalid` key, and defaults to the translation key if none exists.
+func (b *Bundle) Translate(locales []string, fieldError pantopoda.FieldError) string {
+	label, ok := b.Message(locales, "fields."+fieldError.Field, nil)
+	if !ok {
+		label = fieldError.Field
+	}
+
+	params := map[string]string{
+		"field": label,
+		"param": fieldError.Param,
+		"rule":  fieldError.Rule,
+	}
+
+	for _, key := range []string{fieldError.Key, fieldError.Rule, "invalid"} {
+		if key == "" {
+			continue
+		}
+
+		if message, ok := b.Message(locales, key, params); ok {
+			return message
+		}
+	}
+
+	return fieldError.Key
+}
+
+// chain returns the locales to look up the messages in, which are the given
+// locales, each followed by its base language and its fallbacks, and the
+// default locale at last.
+func (b *Bundle) chain(locales []string) []string {
+	chain := make([]string, 0, len(locales)*2+1)
+	seen := make(map[string]bool)
+
+	var add func(locale string)
+	add = func(locale string) {
+		if locale == "" || seen[locale] {
+			return
+		}
+
+		seen[locale] = true
+		chain = append(chain, locale)
+
+		if i := strings.Index(locale, "-"); i > 0 {
+			add(locale[:i])
+		}
+
+		for _, fallback := range b.fallbacks[locale] {
+			add(fallback)
+		}
+	}
+
+	for _, locale := range locales {
+		add(normalize(locale))
+	}
+	add(b.defaultLocale)
+
+	return chain
+}
+
+// ParseAcceptLanguage returns the locales of the Accept-Language header value
+// ordered by their quality.
+func ParseAcceptLanguage(header string) []string {
+	type language struct {
+		locale  string
+		quality float64
+	}
+
+	languages := make([]language, 0)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+
+		locale := normalize(params[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil && strings.HasPrefix(param, "q=") {
+				quality = q
+			}
+		}
+
+		if quality > 0 {
+			languages = append(languages, language{locale: locale, quality: quality})
+		}
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	locales := make([]string, len(languages))
+	for i, l := range languages {
+		locales[i] = l.locale
+	}
+
+	return locales
+}
+
+// RequestLocales returns the locales of the Accept-Language header of the
+// request ordered by their quality.
+func RequestLocales(r *http.Request) []string {
+	if r == nil {
+		return nil
+	}
+
+	return ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// normalize converts the locale into lower case with `-` separator, e.g.
+// `fa_IR` into `fa-ir`.
+func normalize(locale string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(locale), "_", "-", -1))
+}
+
+// interpolate replaces the placeholders of the message with params.
+func interpolate(message string, params map[string]string) string {
+	if len(params) == 0 {
+		return message
+	}
+
+	replacements := make([]string, 0, len(params)*2)
+	for key, value := range params {
+		replacements = append(replacements, "{"+key+"}", value)
+	}
+
+	return strings.NewReplacer(replacements...).Replace(message)
+}
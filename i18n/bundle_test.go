@@ -0,0 +1,120 @@
+package i18n
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Kamva/pantopoda"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := map[string][]string{
+		"":                        {},
+		"fa":                      {"fa"},
+		"en-US,fa;q=0.8,de;q=0.9": {"en-us", "de", "fa"},
+		"fa_IR;q=0.5, en":         {"en", "fa-ir"},
+		"*, fr;q=0, es;q=0.2, it": {"it", "es"},
+		"en;q=invalid, fa;q=0.5":  {"en", "fa"},
+	}
+
+	for header, want := range tests {
+		if got := ParseAcceptLanguage(header); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected the locales of %q to be %v, got %v", header, want, got)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "fa, en;q=0.5")
+	if got := RequestLocales(req); !reflect.DeepEqual(got, []string{"fa", "en"}) {
+		t.Errorf("unexpected request locales %v", got)
+	}
+	if got := RequestLocales(nil); got != nil {
+		t.Errorf("expected no locales without a request, got %v", got)
+	}
+}
+
+func TestBundleMessage(t *testing.T) {
+	bundle := NewBundle("en")
+	bundle.Add("en", Catalog{"greeting": "Hello {name}", "farewell": "Bye"})
+	bundle.Add("pt", Catalog{"greeting": "Olá {name}"})
+	bundle.Add("pt-BR", Catalog{"farewell": "Tchau"})
+	bundle.Add("gl", Catalog{"farewell": "Adeus"})
+	bundle.SetFallback("gl", "pt")
+
+	tests := []struct {
+		locales []string
+		key     string
+		want    string
+	}{
+		{[]string{"pt-BR"}, "farewell", "Tchau"},
+		{[]string{"pt_BR"}, "greeting", "Olá Ali"},
+		{[]string{"gl"}, "greeting", "Olá Ali"},
+		{[]string{"gl"}, "farewell", "Adeus"},
+		{[]string{"de", "pt"}, "greeting", "Olá Ali"},
+		{[]string{"de"}, "farewell", "Bye"},
+		{nil, "greeting", "Hello Ali"},
+	}
+
+	for _, test := range tests {
+		if got, ok := bundle.Message(test.locales, test.key, map[string]string{"name": "Ali"}); !ok || got != test.want {
+			t.Errorf("expected %s in %v to be %q, got %q", test.key, test.locales, test.want, got)
+		}
+	}
+
+	if _, ok := bundle.Message([]string{"pt"}, "missing", nil); ok {
+		t.Error("expected the missing message not to be found")
+	}
+
+	bundle.Add("EN", Catalog{"farewell": "Goodbye"})
+	if got, _ := bundle.Message(nil, "farewell", nil); got != "Goodbye" {
+		t.Errorf("expected the message to be replaced, got %q", got)
+	}
+}
+
+func TestBundleTranslate(t *testing.T) {
+	bundle := NewBundle("en")
+	bundle.Add("en", English)
+	bundle.Add("en", Catalog{
+		"fields.user.email": "email address",
+		"user.name.custom":  "Choose another name.",
+	})
+	bundle.Add("fa", Persian)
+
+	tests := []struct {
+		locales    []string
+		fieldError pantopoda.FieldError
+		want       string
+	}{
+		{nil, pantopoda.FieldError{Field: "name", Rule: "required", Key: "name.required"}, "The name field is required."},
+		{nil, pantopoda.FieldError{Field: "age", Rule: "min", Param: "18", Key: "age.min"}, "The age must be at least 18."},
+		{nil, pantopoda.FieldError{Field: "user.email", Rule: "email", Key: "email"}, "The email address must be a valid email address."},
+		{nil, pantopoda.FieldError{Field: "user.name", Rule: "unique_name", Key: "user.name.custom"}, "Choose another name."},
+		{nil, pantopoda.FieldError{Field: "code", Rule: "custom_rule", Key: "code.custom_rule"}, "The code field is invalid."},
+		{[]string{"fa"}, pantopoda.FieldError{Field: "name", Rule: "required", Key: "name.required"}, "فیلد name الزامی است."},
+		{[]string{"fa"}, pantopoda.FieldError{Field: "user.email", Rule: "required", Key: "required"}, "فیلد email address الزامی است."},
+	}
+
+	for _, test := range tests {
+		if got := bundle.Translate(test.locales, test.fieldError); got != test.want {
+			t.Errorf("expected %+v in %v to be translated into %q, got %q", test.fieldError, test.locales, test.want, got)
+		}
+	}
+
+	empty := NewBundle("en")
+	if got := empty.Translate(nil, pantopoda.FieldError{Field: "name", Rule: "required", Key: "name.required"}); got != "name.required" {
+		t.Errorf("expected the translation key without catalogs, got %q", got)
+	}
+}
+
+func TestDefaultCatalogs(t *testing.T) {
+	for key := range English {
+		if _, ok := Persian[key]; !ok {
+			t.Errorf("expected the Persian catalog to have %s", key)
+		}
+	}
+
+	if len(English) != len(Persian) {
+		t.Errorf("expected the catalogs to have the same keys, got %d and %d", len(English), len(Persian))
+	}
+}
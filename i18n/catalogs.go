@@ -0,0 +1,68 @@
+package i18n
+
+// English is the built-in catalog of validation messages in English, keyed by
+// validation rules. The `invalid` message is used for the other rules.
+var English = Catalog{
+	"invalid":          "The {field} field is invalid.",
+	"required":         "The {field} field is required.",
+	"required_with":    "The {field} field is required when {param} is present.",
+	"required_without": "The {field} field is required when {param} is not present.",
+	"required_if":      "The {field} field is required in this case.",
+	"min":              "The {field} must be at least {param}.",
+	"max":              "The {field} may not be greater than {param}.",
+	"len":              "The {field} must be {param} in length.",
+	"eq":               "The {field} must be equal to {param}.",
+	"ne":               "The {field} must not be equal to {param}.",
+	"gt":               "The {field} must be greater than {param}.",
+	"gte":              "The {field} must be greater than or equal to {param}.",
+	"lt":               "The {field} must be less than {param}.",
+	"lte":              "The {field} must be less than or equal to {param}.",
+	"oneof":            "The {field} must be one of {param}.",
+	"unique":           "The {field} must contain unique values.",
+	"email":            "The {field} must be a valid email address.",
+	"url":              "The {field} must be a valid URL.",
+	"uuid":             "The {field} must be a valid UUID.",
+	"numeric":          "The {field} must be a number.",
+	"alpha":            "The {field} may only contain letters.",
+	"alphanum":         "The {field} may only contain letters and numbers.",
+	"iban":             "The {field} must be a valid IBAN.",
+	"ir_national_code": "The {field} must be a valid national code.",
+}
+
+// Persian is the built-in catalog of validation messages in Persian, keyed by
+// validation rules. The `invalid` message is used for the other rules.
+var Persian = Catalog{
+	"invalid":          "{field} نامعتبر است.",
+	"required":         "فیلد {field} الزامی است.",
+	"required_with":    "فیلد {field} در صورت وجود {param} الزامی است.",
+	"required_without": "فیلد {field} در صورت عدم وجود {param} الزامی است.",
+	"required_if":      "فیلد {field} در این حالت الزامی است.",
+	"min":              "{field} باید حداقل {param} باشد.",
+	"max":              "{field} نباید بیشتر از {param} باشد.",
+	"len":              "طول {field} باید {param} باشد.",
+	"eq":               "{field} باید برابر با {param} باشد.",
+	"ne":               "{field} نباید برابر با {param} باشد.",
+	"gt":               "{field} باید بزرگتر از {param} باشد.",
+	"gte":              "{field} باید بزرگتر یا مساوی {param} باشد.",
+	"lt":               "{field} باید کوچکتر از {param} باشد.",
+	"lte":              "{field} باید کوچکتر یا مساوی {param} باشد.",
+	"oneof":            "{field} باید یکی از مقادیر {param} باشد.",
+	"unique":           "مقادیر {field} باید یکتا باشند.",
+	"email":            "{field} باید یک آدرس ایمیل معتبر باشد.",
+	"url":              "{field} باید یک آدرس اینترنتی معتبر باشد.",
+	"uuid":             "{field} باید یک UUID معتبر باشد.",
+	"numeric":          "{field} باید عدد باشد.",
+	"alpha":            "{field} فقط می‌تواند شامل حروف باشد.",
+	"alphanum":         "{field} فقط می‌تواند شامل حروف و اعداد باشد.",
+	"iban":             "{field} باید یک شماره شبای معتبر باشد.",
+	"ir_national_code": "{field} باید یک کد ملی معتبر باشد.",
+}
+
+// Default is the bundle containing the built-in catalogs, with English as its
+// default locale.
+var Default = NewBundle("en")
+
+func init() {
+	Default.Add("en", English)
+	Default.Add("fa", Persian)
+}
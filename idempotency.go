@@ -0,0 +1,82 @@
+package pantopoda
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kamva/pantopoda/compress"
+)
+
+// IdempotencyKeyHeader is the header carrying the idempotency key of requests.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxRetryDelay is the maximum delay between retries of idempotent requests.
+const maxRetryDelay = 10 * time.Second
+
+// newIdempotencyKey generates a random UUID as idempotency key.
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// shouldRetry checks that the request should be retried, which is the case
+// for transport errors, server errors, and rate limited or in-flight
+// conflicts which state when to retry.
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, compress.ErrTooLarge)
+	}
+
+	switch {
+	case resp.StatusCode >= 500:
+		return true
+	case resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusTooManyRequests:
+		return resp.Header.Get("Retry-After") != ""
+	default:
+		return false
+	}
+}
+
+// retryDelay returns the delay before the next retry, which is the
+// Retry-After of the response if it is given in seconds, or an exponential
+// backoff starting from 100ms.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	delay := 100 * time.Millisecond << uint(attempt)
+
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+	}
+
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	return delay
+}
+
+// wait waits for the delay, or until the ctx is done.
+func wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,107 @@
+package pantopoda
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// newIdempotencyServer starts a server which responds with the statuses in
+// order, and records the idempotency keys of the requests.
+func newIdempotencyServer(t *testing.T, statuses ...int) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		status := statuses[len(keys)-1]
+		mu.Unlock()
+
+		if status == http.StatusConflict || status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string(nil), keys...)
+	}
+}
+
+func TestIdempotencyRetries(t *testing.T) {
+	srv, keys := newIdempotencyServer(t, http.StatusConflict, http.StatusTooManyRequests, http.StatusBadGateway, http.StatusCreated)
+
+	response, err := NewPantopoda(WithIdempotency(3)).Post(srv.URL, Request{Payload: JSONBody{"name": "alice"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusCreated {
+		t.Errorf("expected the request to be retried until it succeeds, got %d", response.StatusCode)
+	}
+
+	sent := keys()
+	if len(sent) != 4 || sent[0] == "" {
+		t.Fatalf("expected 4 requests with an idempotency key, got %v", sent)
+	}
+	for _, key := range sent[1:] {
+		if key != sent[0] {
+			t.Errorf("expected the retries to reuse the key %s, got %s", sent[0], key)
+		}
+	}
+}
+
+func TestIdempotencyNoRetry(t *testing.T) {
+	tests := map[string]struct {
+		retries  int
+		method   string
+		statuses []int
+		key      string
+		sent     int
+	}{
+		"retries exhausted":  {1, http.MethodPost, []int{http.StatusConflict, http.StatusConflict}, "", 2},
+		"client error":       {3, http.MethodPost, []int{http.StatusUnprocessableEntity}, "", 1},
+		"patch client error": {3, http.MethodPatch, []int{http.StatusBadRequest}, "", 1},
+		"not enabled":        {0, http.MethodPost, []int{http.StatusBadGateway}, "", 1},
+		"given key":          {1, http.MethodPost, []int{http.StatusCreated}, "given", 1},
+		"put":                {3, http.MethodPut, []int{http.StatusBadGateway}, "", 1},
+	}
+
+	for name, test := range tests {
+		srv, keys := newIdempotencyServer(t, test.statuses...)
+
+		request := Request{Payload: JSONBody{"name": "alice"}}
+		if test.key != "" {
+			request.Headers = RequestHeaders{IdempotencyKeyHeader: test.key}
+		}
+
+		c := NewPantopoda(WithIdempotency(test.retries))
+		switch test.method {
+		case http.MethodPost:
+			_, _ = c.Post(srv.URL, request)
+		case http.MethodPatch:
+			_, _ = c.Patch(srv.URL, request)
+		case http.MethodPut:
+			_, _ = c.Put(srv.URL, request)
+		}
+
+		sent := keys()
+		if len(sent) != test.sent {
+			t.Errorf("%s: expected %d requests, got %d", name, test.sent, len(sent))
+			continue
+		}
+		if test.key != "" && sent[0] != test.key {
+			t.Errorf("%s: expected the given key to be kept, got %s", name, sent[0])
+		}
+		if test.method == http.MethodPut && sent[0] != "" {
+			t.Errorf("%s: expected no idempotency key, got %s", name, sent[0])
+		}
+	}
+}
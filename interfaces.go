@@ -8,6 +8,7 @@ import (
 
 	"github.com/Kamva/nautilus"
 	code "github.com/Kamva/pantopoda/http"
+	"github.com/Kamva/pantopoda/schema"
 	"github.com/Kamva/shark"
 )
 
@@ -34,6 +35,11 @@ const BadRequest ErrorType = "bad_request"
 // violated in incoming request payload.
 const RuleViolation ErrorType = "validation_failed"
 
+// LookupFailed that returned when the lookup of a context-aware validation
+// rule failed, e.g. due to a database error, so the request payload could not
+// be validated.
+const LookupFailed ErrorType = "lookup_failed"
+
 // ValidationError is an error type containing validation error bag and error
 // type determine type of validation error.
 type ValidationError struct {
@@ -42,6 +48,74 @@ type ValidationError struct {
 
 	// ErrorType is a string determine type of the validation error.
 	ErrorType ErrorType
+
+	// details is kept behind a pointer so that ValidationError stays
+	// comparable.
+	details *validationDetails
+}
+
+// validationDetails contains the details of a validation error, which are
+// reported by the methods of ValidationError.
+type validationDetails struct {
+	fields []FieldError
+	err    error
+}
+
+// FieldError contains the details of a validation error on a request field.
+type FieldError struct {
+	// Field is the path of the field in request payload, e.g. `items[2].price`.
+	Field string
+
+	// Rule is the validation rule that field violated, e.g. `min`.
+	Rule string
+
+	// Param is the parameter of the violated rule, e.g. `3` for `min=3`.
+	Param string
+
+	// Key is the translation key of the validation error.
+	Key string
+}
+
+// Error returns the string representation of the validation error.
+func (e ValidationError) Error() string {
+	if err := e.Unwrap(); err != nil {
+		return fmt.Sprintf("%s: %s", e.ErrorType, err)
+	}
+
+	return fmt.Sprintf("%s: %v", e.ErrorType, e.ErrorBag)
+}
+
+// Fields returns the details of validation errors on request fields, in the
+// order they are reported.
+func (e ValidationError) Fields() []FieldError {
+	if e.details == nil {
+		return nil
+	}
+
+	return e.details.fields
+}
+
+// Unwrap returns the error of the lookup when the error type is LookupFailed.
+func (e ValidationError) Unwrap() error {
+	if e.details == nil {
+		return nil
+	}
+
+	return e.details.err
+}
+
+// addField adds the details of a validation error on a request field.
+func (e *ValidationError) addField(fieldError FieldError) {
+	if e.details == nil {
+		e.details = new(validationDetails)
+	}
+
+	e.details.fields = append(e.details.fields, fieldError)
+}
+
+// Failed checks that the validation has failed.
+func (e ValidationError) Failed() bool {
+	return e.ErrorType != ""
 }
 
 // RequestHeaders represents the key-value pairs in an HTTP header.
@@ -74,6 +148,21 @@ func (body JSONArray) ToJSON() []byte {
 	return b
 }
 
+// StructBody represents the json body of a struct value, which is encoded
+// using its json tags. The value is validated before sending when the client
+// has validation enabled.
+type StructBody struct {
+	Value interface{}
+}
+
+// ToJSON converts the struct value to json bytes
+func (body StructBody) ToJSON() []byte {
+	b, err := json.Marshal(body.Value)
+	shark.PanicIfError(err)
+
+	return b
+}
+
 // QueryParams represent url query params.
 type QueryParams map[string][]string
 
@@ -110,6 +199,14 @@ type Request struct {
 
 	// Headers represent headers of HTTP call.
 	Headers RequestHeaders
+
+	// ResponseSchema is the JSON Schema that the body of a successful
+	// response is validated against, if it is set.
+	ResponseSchema *schema.Schema
+
+	// BalanceKey is the key the request is routed by, when the client
+	// balances the requests using ConsistentHash, e.g. the ID of a user.
+	BalanceKey string
 }
 
 // HasBody checks that request has payload
@@ -122,12 +219,22 @@ type Response struct {
 	json       []byte
 	StatusCode code.StatusCode
 	Headers    http.Header
+	validate   bool
 }
 
 // Unmarshal parses the JSON-encoded response and stores the result in the value
-// pointed to by v.
+// pointed to by v. When the client has validation enabled, the value is then
+// validated against its `validate` tags.
 func (r Response) Unmarshal(v interface{}) error {
-	return json.Unmarshal(r.json, v)
+	if err := json.Unmarshal(r.json, v); err != nil {
+		return err
+	}
+
+	if r.validate {
+		return validateResponse(v)
+	}
+
+	return nil
 }
 
 // ToString convert the response body to its string value.
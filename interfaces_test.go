@@ -0,0 +1,21 @@
+package pantopoda
+
+import "testing"
+
+func TestQueryParamsToString(t *testing.T) {
+	tests := []struct {
+		query QueryParams
+		want  string
+	}{
+		{QueryParams{}, ""},
+		{QueryParams{"page": {"2"}}, "page=[2]"},
+		{QueryParams{"ids": {"1", "2"}}, "ids[]=1&ids[]=2"},
+		{QueryParams{"q": {"a%20b"}}, "q=[a%20b]"},
+	}
+
+	for _, test := range tests {
+		if got := test.query.ToString(); got != test.want {
+			t.Errorf("expected %v to be encoded as %q, got %q", test.query, test.want, got)
+		}
+	}
+}
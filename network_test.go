@@ -0,0 +1,264 @@
+package pantopoda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newUnixServer starts a server on a Unix socket at the path, which responds
+// the request URI and counts the requests.
+func newUnixServer(t *testing.T, path string, hits *int64) {
+	t.Helper()
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(hits, 1)
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/users/1", http.StatusFound)
+			return
+		}
+
+		fmt.Fprintf(w, `{"uri":%q}`, r.URL.RequestURI())
+	})}
+	go srv.Serve(listener)
+	t.Cleanup(func() { srv.Close() })
+}
+
+func requestURI(t *testing.T, c *Pantopoda, endpoint string) string {
+	t.Helper()
+
+	response, err := c.Get(endpoint, Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body struct {
+		URI string `json:"uri"`
+	}
+	if err := response.Unmarshal(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	return body.URI
+}
+
+func TestUnixSocketEndpoint(t *testing.T) {
+	var hits int64
+	path := filepath.Join(t.TempDir(), "api:v1.sock")
+	newUnixServer(t, path, &hits)
+
+	c := NewPantopoda()
+	if uri := requestURI(t, c, UnixSocket(path)+"/users/1?fields=id"); uri != "/users/1?fields=id" {
+		t.Errorf("expected request URI %q, got %q", "/users/1?fields=id", uri)
+	}
+
+	if uri := requestURI(t, c, UnixSocket(path)+"/redirect"); uri != "/users/1" {
+		t.Errorf("expected redirect within the socket to %q, got %q", "/users/1", uri)
+	}
+}
+
+func TestUnixSocketLongPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), strings.Repeat("d", 50))
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	var hits int64
+	path := filepath.Join(dir, "sidecar.sock")
+	newUnixServer(t, path, &hits)
+
+	if uri := requestURI(t, NewPantopoda(), UnixSocket(path)+"/health"); uri != "/health" {
+		t.Errorf("expected request URI %q, got %q", "/health", uri)
+	}
+}
+
+func TestUnixSocketBalancer(t *testing.T) {
+	var hits int64
+	path := filepath.Join(t.TempDir(), "api.sock")
+	newUnixServer(t, path, &hits)
+
+	c := NewPantopoda(WithBalancer(BalancerConfig{Resolver: StaticResolver(UnixSocket(path) + "/v1")}))
+	if uri := requestURI(t, c, "/users/2"); uri != "/v1/users/2" {
+		t.Errorf("expected request URI %q, got %q", "/v1/users/2", uri)
+	}
+}
+
+func TestUnixSocketRedirect(t *testing.T) {
+	var hits int64
+	path := filepath.Join(t.TempDir(), "docker.sock")
+	newUnixServer(t, path, &hits)
+
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://"+unixHost(path)+"/containers/json", http.StatusFound)
+	}))
+	defer remote.Close()
+
+	c := NewPantopoda()
+
+	// A request to the socket leaves an idle connection behind, which must
+	// not be reused for the redirect either.
+	requestURI(t, c, UnixSocket(path)+"/")
+
+	_, err := c.Get(remote.URL, Request{})
+	if !errors.Is(err, errUnixRedirect) {
+		t.Errorf("expected the redirect to the socket to be rejected, got %v", err)
+	}
+	if atomic.LoadInt64(&hits) != 1 {
+		t.Errorf("expected the socket not to be requested by the redirect, got %d requests", hits)
+	}
+
+	// Without the redirect check, the host is still not dialed unless the
+	// request is marked with its socket.
+	d := newDialer()
+	if _, err := d.dialContext(context.Background(), "tcp", unixHost(path)+":80"); err == nil {
+		t.Error("expected the unmarked socket host not to be dialed")
+	}
+}
+
+func TestHostPatterns(t *testing.T) {
+	tests := []struct {
+		hosts string
+		url   string
+		match bool
+	}{
+		{"*", "http://example.com", true},
+		{"example.com", "http://example.com", true},
+		{"example.com", "http://api.example.com", true},
+		{"example.com", "http://badexample.com", false},
+		{".example.com", "http://example.com", false},
+		{".example.com", "http://api.example.com", true},
+		{"*.example.com", "http://api.example.com", true},
+		{"example.com:8080", "http://example.com:8080", true},
+		{"example.com:8080", "http://example.com", false},
+		{"example.com:443", "https://example.com", true},
+		{"10.0.0.0/8", "http://10.1.2.3", true},
+		{"10.0.0.0/8", "http://11.1.2.3", false},
+		{"10.0.0.0/8", "http://example.com", false},
+		{"192.168.1.1", "http://192.168.1.1:8080", true},
+		{"[::1]", "http://[::1]:8080", true},
+		{"foo.com, Example.COM", "http://example.com", true},
+	}
+
+	for _, test := range tests {
+		u, err := url.Parse(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rule := proxyRule{hosts: parseHostPatterns(test.hosts)}
+		if got := rule.matches(u); got != test.match {
+			t.Errorf("expected %q matching %s to be %v, got %v", test.hosts, test.url, test.match, got)
+		}
+	}
+}
+
+func TestWithProxy(t *testing.T) {
+	c := NewPantopoda(WithProxy(
+		ProxyRule{Hosts: ".internal, 10.0.0.0/8"},
+		ProxyRule{Hosts: "*", Proxy: "http://proxy:3128"},
+	))
+
+	tests := map[string]string{
+		"http://api.internal/users": "",
+		"http://10.1.2.3/users":     "",
+		"http://example.com/users":  "http://proxy:3128",
+	}
+
+	for endpoint, want := range tests {
+		req, err := http.NewRequest("GET", endpoint, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		proxy, err := c.proxy(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := ""
+		if proxy != nil {
+			got = proxy.String()
+		}
+		if got != want {
+			t.Errorf("expected proxy %q for %s, got %q", want, endpoint, got)
+		}
+	}
+}
+
+func TestWithProxyRequest(t *testing.T) {
+	var host string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.URL.Host
+		w.Write([]byte(`{}`))
+	}))
+	defer proxy.Close()
+
+	c := NewPantopoda(WithProxy(ProxyRule{Hosts: "example.com", Proxy: proxy.URL}))
+	if _, err := c.Get("http://api.example.com/users", Request{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if host != "api.example.com" {
+		t.Errorf("expected the request to api.example.com through the proxy, got %q", host)
+	}
+}
+
+func TestWithDNSCache(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var addresses []string
+	dial := func(ctx context.Context, network string, address string) (net.Conn, error) {
+		addresses = append(addresses, address)
+
+		var d net.Dialer
+		return d.DialContext(ctx, network, address)
+	}
+
+	c := NewPantopoda(WithDialer(dial), WithDNSCache(time.Minute))
+	c.dialer.dns.entries["api.example.com"] = dnsEntry{ips: []string{"127.0.0.1"}, expiresAt: time.Now().Add(time.Minute)}
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	if _, err := c.Get("http://api.example.com:"+port+"/", Request{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(addresses) != 1 || addresses[0] != "127.0.0.1:"+port {
+		t.Errorf("expected the cached address to be dialed, got %v", addresses)
+	}
+}
+
+func TestDNSCacheStale(t *testing.T) {
+	cache := &dnsCache{ttl: time.Minute, entries: map[string]dnsEntry{
+		"stale.invalid": {ips: []string{"127.0.0.1"}, expiresAt: time.Now().Add(-time.Second)},
+	}}
+
+	ips, err := cache.lookup(context.Background(), "stale.invalid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || ips[0] != "127.0.0.1" {
+		t.Errorf("expected the stale addresses when the lookup fails, got %v", ips)
+	}
+
+	if _, err := cache.lookup(context.Background(), "missing.invalid"); err == nil {
+		t.Error("expected the lookup of an unknown host to fail")
+	}
+}
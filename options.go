@@ -0,0 +1,74 @@
+package pantopoda
+
+import "net/http"
+
+// Option configures the Pantopoda client.
+type Option func(c *Pantopoda)
+
+// WithValidation enables the client-side validation. Struct payloads are
+// validated against their `validate` tags before sending the request, and
+// the values decoded by Response.Unmarshal are validated after decoding.
+// Validation failures are returned as PayloadValidationError.
+func WithValidation() Option {
+	return func(c *Pantopoda) {
+		c.validate = true
+	}
+}
+
+// WithRequestCompression compresses the request bodies with the content
+// coding, e.g. compress.Gzip, and sends them with Content-Encoding header.
+// Only use it for servers known to accept the coding.
+func WithRequestCompression(encoding string) Option {
+	return func(c *Pantopoda) {
+		c.requestEncoding = encoding
+	}
+}
+
+// WithMaxResponseSize limits the size of decoded response bodies, in bytes,
+// which protects against decompression bombs. Reading larger bodies fails
+// with compress.ErrTooLarge. It is DefaultMaxResponseSize by default, and a
+// limit less than or equal to zero means no limit.
+func WithMaxResponseSize(limit int64) Option {
+	return func(c *Pantopoda) {
+		c.maxResponseSize = limit
+	}
+}
+
+// WithIdempotency attaches a generated Idempotency-Key header to POST and
+// PATCH requests which do not have one, and retries them up to retries times
+// with the same key on transport errors, server errors, and 409 and 429
+// responses with Retry-After header. The server must support idempotency
+// keys, e.g. using api.Idempotency, for the retries to be safe.
+func WithIdempotency(retries int) Option {
+	return func(c *Pantopoda) {
+		c.idempotencyRetries = retries
+	}
+}
+
+// WithHedging enables hedged GET and HEAD requests with the policy, which
+// reduces the tail latency of calls to replicated services. The metrics of
+// hedging are reported by HedgeStats.
+func WithHedging(policy HedgePolicy) Option {
+	return func(c *Pantopoda) {
+		c.hedger = newHedger(policy)
+	}
+}
+
+// WithBalancer balances the requests between the instances of a service
+// resolved by the resolver of config. The endpoints of requests are then
+// relative to the base URLs of instances, e.g. `/users/1`, while absolute
+// endpoints are sent as they are.
+func WithBalancer(config BalancerConfig) Option {
+	return func(c *Pantopoda) {
+		c.balancer = newBalancer(config)
+	}
+}
+
+// WithCookieJar stores the cookies of responses in the jar, e.g. a
+// CookieJar, and sends them with the matching requests, which keeps the
+// session of services requiring login.
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(c *Pantopoda) {
+		c.client.Jar = jar
+	}
+}
@@ -0,0 +1,407 @@
+package pantopoda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Pagination describes where the items and the position of the next page are
+// found in the responses of a paginated endpoint. Its zero value matches the
+// payloads generated by the api package.
+type Pagination struct {
+	// ItemsField is the dotted path of the items array in the response,
+	// `data` by default.
+	ItemsField string
+
+	// CursorField is the dotted path of the next page cursor in the response,
+	// `meta.next_cursor` by default.
+	CursorField string
+
+	// CursorParam is the query param the cursor is sent in, `cursor` by
+	// default.
+	CursorParam string
+
+	// PageField is the dotted path of the current page number in the
+	// response, `meta.page` by default.
+	PageField string
+
+	// TotalPagesField is the dotted path of the number of pages in the
+	// response, `meta.total_pages` by default.
+	TotalPagesField string
+
+	// PageParam is the query param the page number is sent in, `page` by
+	// default.
+	PageParam string
+}
+
+func (p Pagination) withDefaults() Pagination {
+	defaults := map[*string]string{
+		&p.ItemsField:      "data",
+		&p.CursorField:     "meta.next_cursor",
+		&p.CursorParam:     "cursor",
+		&p.PageField:       "meta.page",
+		&p.TotalPagesField: "meta.total_pages",
+		&p.PageParam:       "page",
+	}
+
+	for field, value := range defaults {
+		if *field == "" {
+			*field = value
+		}
+	}
+
+	return p
+}
+
+// Iterator iterates over the items of a paginated endpoint, fetching the
+// pages as needed. The next page is found by following the `next` link of
+// the Link header, the cursor of the response, or the page number of the
+// response, in that order.
+//
+//	it := client.Paginate(ctx, endpoint, request)
+//	for it.Next() {
+//		var user User
+//		if err := it.Scan(&user); err != nil {
+//			return err
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type Iterator struct {
+	client     *Pantopoda
+	ctx        context.Context
+	endpoint   string
+	request    Request
+	params     url.Values
+	pagination Pagination
+
+	items []json.RawMessage
+	item  json.RawMessage
+	done  bool
+	err   error
+}
+
+// Paginate returns an iterator over the items of the paginated endpoint. The
+// pages are fetched using GET requests with the given request data.
+func (c *Pantopoda) Paginate(ctx context.Context, endpoint string, request Request, pagination ...Pagination) *Iterator {
+	p := Pagination{}
+	if len(pagination) > 0 {
+		p = pagination[0]
+	}
+
+	return &Iterator{
+		client:     c,
+		ctx:        ctx,
+		endpoint:   endpoint,
+		request:    request,
+		pagination: p.withDefaults(),
+	}
+}
+
+// Next advances the iterator to the next item, fetching the next page if
+// needed. It returns false when the items are exhausted, the context is done
+// or an error occurs.
+func (it *Iterator) Next() bool {
+	for len(it.items) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		it.fetch()
+	}
+
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+
+	it.item, it.items = it.items[0], it.items[1:]
+	return true
+}
+
+// Scan decodes the current item into the value pointed to by v. The value is
+// validated when the client has validation enabled.
+func (it *Iterator) Scan(v interface{}) error {
+	return Response{json: it.item, validate: it.client.validate}.Unmarshal(v)
+}
+
+// Err returns the error occurred during the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// fetch fetches the current page and prepares the request of the next page.
+func (it *Iterator) fetch() {
+	if it.err = it.ctx.Err(); it.err != nil {
+		return
+	}
+
+	request := it.request
+	request.Query = nil
+
+	response, err := it.client.RequestWithContext(it.ctx, "GET", it.url(), request)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	var body interface{}
+	if err = json.Unmarshal(response.json, &body); err != nil {
+		it.err = err
+		return
+	}
+
+	value := lookupField(body, it.pagination.ItemsField)
+	items, ok := value.([]interface{})
+	if !ok && value != nil {
+		it.err = fmt.Errorf("pantopoda: no items array at %q of the response", it.pagination.ItemsField)
+		return
+	}
+
+	it.items = make([]json.RawMessage, len(items))
+	for i, item := range items {
+		if it.items[i], err = json.Marshal(item); err != nil {
+			it.err = err
+			return
+		}
+	}
+
+	it.done = !it.advance(strings.Join(response.Headers["Link"], ","), body, len(items))
+}
+
+// advance prepares the request of the next page, and returns false if there
+// is no next page. The iteration ends after an empty page, or when the next
+// page is the current page, so that servers repeating the same page do not
+// make the iterator loop forever.
+func (it *Iterator) advance(link string, body interface{}, count int) bool {
+	if count == 0 {
+		return false
+	}
+
+	if reference := nextLink(link); reference != "" {
+		current := it.url()
+		next, err := resolveURL(current, reference)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		if sameURL(current, next) {
+			return false
+		}
+
+		it.endpoint, it.request.Query, it.params = next, nil, nil
+		return true
+	}
+
+	if cursor := lookupField(body, it.pagination.CursorField); cursor != nil {
+		next := fmt.Sprint(cursor)
+		if next == "" || next == it.param(it.pagination.CursorParam) {
+			return false
+		}
+
+		it.setQuery(it.pagination.CursorParam, next)
+		return true
+	}
+
+	page, ok := number(lookupField(body, it.pagination.PageField))
+	if !ok {
+		return false
+	}
+
+	if totalPages, ok := number(lookupField(body, it.pagination.TotalPagesField)); ok && page >= totalPages {
+		return false
+	}
+
+	it.setQuery(it.pagination.PageParam, strconv.Itoa(page+1))
+	return true
+}
+
+// setQuery sets the query param of the next page request, keeping the other
+// params of the request.
+func (it *Iterator) setQuery(key string, value string) {
+	if it.params == nil {
+		it.params = make(url.Values)
+	}
+
+	it.params.Set(key, value)
+}
+
+// param returns the value of the query param of the current page request.
+func (it *Iterator) param(key string) string {
+	if value, ok := it.params[key]; ok {
+		return value[0]
+	}
+
+	if value := it.request.Query[key]; len(value) > 0 {
+		return value[0]
+	}
+
+	return ""
+}
+
+// url returns the URL of the current page, i.e. the endpoint along with the
+// query params of the request and the params of the page, which replace the
+// request params of the same name.
+func (it *Iterator) url() string {
+	query := make(QueryParams, len(it.request.Query))
+	for key, value := range it.request.Query {
+		if _, ok := it.params[key]; !ok {
+			query[key] = value
+		}
+	}
+
+	params := make([]string, 0, 2)
+	if !query.Empty() {
+		params = append(params, query.ToString())
+	}
+	if len(it.params) > 0 {
+		params = append(params, it.params.Encode())
+	}
+
+	if len(params) == 0 {
+		return it.endpoint
+	}
+
+	separator := "?"
+	if strings.Contains(it.endpoint, "?") {
+		separator = "&"
+	}
+
+	return it.endpoint + separator + strings.Join(params, "&")
+}
+
+// nextLink returns the target of the `next` link of the RFC 8288 Link
+// header, or empty string if there is none.
+func nextLink(header string) string {
+	for {
+		header = strings.TrimLeft(header, " \t,")
+		if !strings.HasPrefix(header, "<") {
+			return ""
+		}
+
+		// The target may contain commas and semicolons, so it ends at `>`.
+		end := strings.IndexByte(header, '>')
+		if end < 0 {
+			return ""
+		}
+		target, params := header[1:end], header[end+1:]
+
+		// The params end at the next comma which is not quoted.
+		header = ""
+		if i := indexUnquoted(params, ','); i >= 0 {
+			params, header = params[:i], params[i+1:]
+		}
+
+		if hasRel(params, "next") {
+			return target
+		}
+	}
+}
+
+// hasRel checks that the params of a link contain the relation type.
+func hasRel(params string, relation string) bool {
+	for params != "" {
+		param := params
+		params = ""
+		if i := indexUnquoted(param, ';'); i >= 0 {
+			param, params = param[:i], param[i+1:]
+		}
+
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), "rel") {
+			continue
+		}
+
+		for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(kv[1]), `"`)) {
+			if strings.EqualFold(rel, relation) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// indexUnquoted returns the index of the first instance of c in s which is
+// not inside a quoted string, or -1 if there is none.
+func indexUnquoted(s string, c byte) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == c && !quoted:
+			return i
+		}
+	}
+
+	return -1
+}
+
+// resolveURL resolves the link reference against the url of the request.
+func resolveURL(rawURL string, reference string) (string, error) {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := url.Parse(reference)
+	if err != nil {
+		return "", err
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+// sameURL checks that the URLs are the same, regardless of the order of
+// their query params.
+func sameURL(a string, b string) bool {
+	u, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+
+	v, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+
+	u.RawQuery, v.RawQuery = u.Query().Encode(), v.Query().Encode()
+	u.Fragment, v.Fragment = "", ""
+
+	return u.String() == v.String()
+}
+
+// lookupField returns the value at the dotted path of the decoded json value.
+func lookupField(value interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+
+	return value
+}
+
+// number converts the decoded json number or numeric string into int.
+func number(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,146 @@
+package pantopoda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// collect iterates over the items of the endpoint, and returns them along
+// with the number of requested pages.
+func collect(t *testing.T, handler http.HandlerFunc, endpoint string, pagination ...Pagination) ([]int, int) {
+	t.Helper()
+
+	pages := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		if pages > 10 {
+			t.Fatal("too many pages requested")
+		}
+		handler(w, r)
+	}))
+	defer srv.Close()
+
+	it := NewPantopoda().Paginate(context.Background(), srv.URL+endpoint, Request{}, pagination...)
+
+	items := make([]int, 0)
+	for it.Next() {
+		var item int
+		if err := it.Scan(&item); err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, item)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return items, pages
+}
+
+func assertItems(t *testing.T, items []int, want ...int) {
+	t.Helper()
+
+	if fmt.Sprint(items) != fmt.Sprint(want) {
+		t.Errorf("expected items %v, got %v", want, items)
+	}
+}
+
+func TestIteratorLink(t *testing.T) {
+	items, pages := collect(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", `</items?ids=1,2&page=2>; rel="next", </items?page=9>; rel="last"`)
+			w.Write([]byte(`{"data":[1,2]}`))
+		case "2":
+			if r.URL.Query().Get("ids") != "1,2" {
+				t.Errorf("expected the link with commas to be followed, got %s", r.URL)
+			}
+			w.Write([]byte(`{"data":[3]}`))
+		}
+	}, "/items")
+
+	assertItems(t, items, 1, 2, 3)
+	if pages != 2 {
+		t.Errorf("expected 2 pages, got %d", pages)
+	}
+}
+
+func TestIteratorCursor(t *testing.T) {
+	items, _ := collect(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Write([]byte(`{"data":[1],"meta":{"next_cursor":"a+b/c="}}`))
+		case "a+b/c=":
+			w.Write([]byte(`{"data":[2],"meta":{"next_cursor":""}}`))
+		default:
+			t.Errorf("unexpected cursor %q", r.URL.Query().Get("cursor"))
+		}
+	}, "/items")
+
+	assertItems(t, items, 1, 2)
+}
+
+func TestIteratorPages(t *testing.T) {
+	items, pages := collect(t, func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("p"))
+		if page == 0 {
+			page = 1
+		}
+		fmt.Fprintf(w, `{"items":[%d],"page":{"number":%d,"total":3}}`, page, page)
+	}, "/items", Pagination{ItemsField: "items", PageField: "page.number", TotalPagesField: "page.total", PageParam: "p"})
+
+	assertItems(t, items, 1, 2, 3)
+	if pages != 3 {
+		t.Errorf("expected 3 pages, got %d", pages)
+	}
+}
+
+func TestIteratorStops(t *testing.T) {
+	tests := map[string]http.HandlerFunc{
+		"empty page with next link": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", `</items?page=2>; rel="next"`)
+			w.Write([]byte(`{"data":[]}`))
+		},
+		"next link to the current page": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", `</items>; rel="next"`)
+			w.Write([]byte(`{"data":[1]}`))
+		},
+		"repeated cursor": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"data":[1],"meta":{"next_cursor":"same"}}`))
+		},
+		"empty page with cursor": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"data":[],"meta":{"next_cursor":"next"}}`))
+		},
+	}
+
+	for name, handler := range tests {
+		_, pages := collect(t, handler, "/items")
+		if pages > 2 {
+			t.Errorf("%s: expected the iteration to stop, got %d pages", name, pages)
+		}
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := map[string]string{
+		`</items?page=2>; rel="next"`:                              "/items?page=2",
+		`</items?page=2>; rel=next`:                                "/items?page=2",
+		`</items?page=1>; rel="prev", </items?page=3>; rel="next"`: "/items?page=3",
+		`</items?ids=1,2;3>; rel="next"`:                           "/items?ids=1,2;3",
+		`</a>; title="x, y; z", </b>; rel="next"`:                  "/b",
+		`</items?page=2>; rel="prev next"`:                         "/items?page=2",
+		`</items?page=2>; REL="Next"`:                              "/items?page=2",
+		`</items?page=9>; rel="last"`:                              "",
+		``:                                                         "",
+	}
+
+	for header, want := range tests {
+		if got := nextLink(header); got != want {
+			t.Errorf("expected next link of %q to be %q, got %q", header, want, got)
+		}
+	}
+}
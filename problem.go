@@ -0,0 +1,75 @@
+package pantopoda
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// ProblemDetails represents the RFC 7807 problem details of an error response.
+type ProblemDetails struct {
+	// Type is a URI reference that identifies the problem type.
+	Type string `json:"type"`
+
+	// Title is a short, human-readable summary of the problem type.
+	Title string `json:"title"`
+
+	// Status is the HTTP status code generated by the origin server.
+	Status int `json:"status"`
+
+	// Detail is a human-readable explanation specific to this occurrence of
+	// the problem.
+	Detail string `json:"detail"`
+
+	// Instance is a URI reference that identifies the specific occurrence of
+	// the problem.
+	Instance string `json:"instance"`
+
+	// Extensions contains any members of the problem details other than the
+	// standard ones, such as `code` and `errors`.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// ParseProblem parses the RFC 7807 problem details json document.
+func ParseProblem(body []byte) (ProblemDetails, error) {
+	type standard ProblemDetails
+
+	var problem ProblemDetails
+	if err := json.Unmarshal(body, (*standard)(&problem)); err != nil {
+		return ProblemDetails{}, err
+	}
+
+	members := make(map[string]interface{})
+	if err := json.Unmarshal(body, &members); err != nil {
+		return ProblemDetails{}, err
+	}
+
+	for _, key := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, key)
+	}
+
+	if len(members) > 0 {
+		problem.Extensions = members
+	}
+
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+
+	return problem, nil
+}
+
+// Code returns the `code` extension member of the problem details.
+func (p ProblemDetails) Code() string {
+	code, _ := p.Extensions["code"].(string)
+	return code
+}
+
+// isProblem checks that the response has the problem details content type.
+func isProblem(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediaType == ProblemContentType
+}
@@ -0,0 +1,58 @@
+package pantopoda
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseProblem(t *testing.T) {
+	problem, err := ParseProblem([]byte(`{"title":"Not Found","status":404,"detail":"no such user.","code":"user_not_found","errors":{"id":["invalid"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if problem.Type != "about:blank" || problem.Title != "Not Found" || problem.Status != 404 || problem.Detail != "no such user." {
+		t.Errorf("unexpected problem details %+v", problem)
+	}
+	if problem.Code() != "user_not_found" || problem.Extensions["errors"] == nil || len(problem.Extensions) != 2 {
+		t.Errorf("unexpected extension members %v", problem.Extensions)
+	}
+
+	if _, err = ParseProblem([]byte(`[]`)); err == nil {
+		t.Error("expected a non-object document to be rejected")
+	}
+}
+
+func TestResponseErrorProblem(t *testing.T) {
+	tests := map[string]bool{
+		"application/problem+json":                true,
+		"application/problem+json; charset=utf-8": true,
+		"application/json":                        false,
+	}
+
+	for contentType, isProblem := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"type":"https://example.com/problems/user_not_found","status":404,"code":"user_not_found"}`))
+		}))
+
+		_, err := NewPantopoda().Get(srv.URL, Request{})
+		srv.Close()
+
+		var responseError ResponseError
+		if !errors.As(err, &responseError) {
+			t.Fatalf("expected a response error, got %v", err)
+		}
+
+		if (responseError.Problem != nil) != isProblem {
+			t.Errorf("%s: expected the problem details to be parsed %t, got %+v", contentType, isProblem, responseError.Problem)
+			continue
+		}
+		if isProblem && responseError.Problem.Code() != "user_not_found" {
+			t.Errorf("unexpected problem details %+v", responseError.Problem)
+		}
+	}
+}
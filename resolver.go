@@ -0,0 +1,197 @@
+package pantopoda
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint is a base URL of a service instance, e.g. `http://10.0.0.2:8080`,
+// along with its weight for weighted strategies.
+type Endpoint struct {
+	URL    string
+	Weight int
+}
+
+// Resolver resolves the endpoints of a service.
+type Resolver interface {
+	Resolve(ctx context.Context) ([]Endpoint, error)
+}
+
+// ResolverFunc is an adapter to allow the use of ordinary functions as
+// Resolver.
+type ResolverFunc func(ctx context.Context) ([]Endpoint, error)
+
+// Resolve calls f(ctx).
+func (f ResolverFunc) Resolve(ctx context.Context) ([]Endpoint, error) {
+	return f(ctx)
+}
+
+// StaticResolver returns a resolver of the fixed list of base URLs, each
+// having weight 1.
+func StaticResolver(urls ...string) Resolver {
+	endpoints := make([]Endpoint, len(urls))
+	for i, u := range urls {
+		endpoints[i] = Endpoint{URL: u, Weight: 1}
+	}
+
+	return ResolverFunc(func(context.Context) ([]Endpoint, error) {
+		return endpoints, nil
+	})
+}
+
+// SRVResolver returns a resolver of the DNS SRV records of the service, e.g.
+// `_api._tcp.users.example.com` for SRVResolver("api", "tcp",
+// "users.example.com", "http"). The base URLs are built from the scheme and
+// the target and port of records, weighted by the weight of records. Only
+// the records with the lowest priority are used, and the records are looked
+// up again after the ttl.
+func SRVResolver(service string, proto string, name string, scheme string, ttl time.Duration) Resolver {
+	return &cachedResolver{ttl: ttl, resolve: func(ctx context.Context) ([]Endpoint, error) {
+		_, records, err := net.DefaultResolver.LookupSRV(ctx, service, proto, name)
+		if err != nil {
+			return nil, err
+		}
+
+		endpoints := make([]Endpoint, 0, len(records))
+		for _, record := range records {
+			if record.Priority != records[0].Priority {
+				break
+			}
+
+			endpoints = append(endpoints, Endpoint{
+				URL:    fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port)))),
+				Weight: int(record.Weight),
+			})
+		}
+
+		return endpoints, nil
+	}}
+}
+
+// FileResolver returns a resolver of the base URLs listed in the file, one
+// per line, optionally followed by the weight, e.g. `http://10.0.0.2:8080 3`.
+// Empty lines and lines starting with `#` are ignored. The file is watched
+// for changes, and read again when it is modified.
+func FileResolver(path string) Resolver {
+	return &fileResolver{path: path}
+}
+
+// cachedResolver caches the endpoints for the ttl.
+type cachedResolver struct {
+	ttl     time.Duration
+	resolve func(ctx context.Context) ([]Endpoint, error)
+
+	mu        sync.Mutex
+	endpoints []Endpoint
+	expiresAt time.Time
+}
+
+func (r *cachedResolver) Resolve(ctx context.Context) ([]Endpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.endpoints != nil && time.Now().Before(r.expiresAt) {
+		return r.endpoints, nil
+	}
+
+	endpoints, err := r.resolve(ctx)
+	if err != nil {
+		// Stale endpoints are better than none when the lookup fails.
+		if r.endpoints != nil {
+			return r.endpoints, nil
+		}
+		return nil, err
+	}
+
+	r.endpoints, r.expiresAt = endpoints, time.Now().Add(r.ttl)
+	return endpoints, nil
+}
+
+// fileResolver reads the endpoints from a file, when its modification time
+// changes.
+type fileResolver struct {
+	path string
+
+	mu        sync.Mutex
+	endpoints []Endpoint
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// fileCheckInterval is the minimum interval between checking the file for
+// changes.
+const fileCheckInterval = time.Second
+
+func (r *fileResolver) Resolve(context.Context) ([]Endpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.endpoints != nil && now.Sub(r.checkedAt) < fileCheckInterval {
+		return r.endpoints, nil
+	}
+	r.checkedAt = now
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return r.stale(err)
+	}
+
+	if r.endpoints != nil && info.ModTime().Equal(r.modTime) {
+		return r.endpoints, nil
+	}
+
+	endpoints, err := readEndpoints(r.path)
+	if err != nil {
+		return r.stale(err)
+	}
+
+	r.endpoints, r.modTime = endpoints, info.ModTime()
+	return endpoints, nil
+}
+
+func (r *fileResolver) stale(err error) ([]Endpoint, error) {
+	if r.endpoints != nil {
+		return r.endpoints, nil
+	}
+
+	return nil, err
+}
+
+// readEndpoints reads the endpoints listed in the file.
+func readEndpoints(path string) ([]Endpoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	endpoints := make([]Endpoint, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		endpoint := Endpoint{URL: fields[0], Weight: 1}
+		if len(fields) > 1 {
+			weight, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid weight of %s: %s", fields[0], fields[1])
+			}
+			endpoint.Weight = weight
+		}
+
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints, scanner.Err()
+}
@@ -0,0 +1,237 @@
+package pantopoda
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Kamva/orca"
+	"github.com/Kamva/shark"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// Lookup checks the existence of values in an external source, such as a
+// database, for context-aware validation rules.
+type Lookup interface {
+	// Exists checks that the value exists in the source identified by param,
+	// which is the parameter of the validation rule, e.g. `users.email`.
+	Exists(ctx context.Context, param string, value interface{}) (bool, error)
+}
+
+// registration applies a registered rule to a validator.
+type registration func(v *validator.Validate) error
+
+var rulesMu sync.Mutex
+
+// rules contains the registered rules, which are applied to the validator
+// when they are registered. The built-in rules are registered by default.
+var rules = []registration{
+	tagRule("iban", isIBAN, false),
+	tagRule("ir_national_code", isIranianNationalCode, false),
+	tagRule("required_if", requiredIf, true),
+}
+
+// configured is the validator that the registered rules are applied to.
+var configured *validator.Validate
+
+// applied is the number of registered rules applied to the configured
+// validator.
+var applied int
+
+// ready is set once the rules are applied to the validator on its first use,
+// so that validations do not take rulesMu afterwards.
+var ready uint32
+
+// RegisterRule registers the validation function for the tag, to be used in
+// `validate` tags of request data. The function is not called for nil values
+// unless callEvenIfNull is true.
+//
+// Rules are applied to the shared validator when they are registered, which
+// is not safe while validations are running, so all rules must be registered
+// before use, e.g. in init functions.
+func RegisterRule(tag string, fn validator.Func, callEvenIfNull bool) {
+	register(tagRule(tag, fn, callEvenIfNull))
+}
+
+// RegisterContextRule registers the context-aware validation function for the
+// tag. The function receives the context passed to ValidateContext. Like
+// RegisterRule, it must be called before use.
+func RegisterContextRule(tag string, fn validator.FuncCtx, callEvenIfNull bool) {
+	register(func(v *validator.Validate) error {
+		return v.RegisterValidationCtx(tag, fn, callEvenIfNull)
+	})
+}
+
+// RegisterLookupRule registers a context-aware rule for the tag, which passes
+// when the field value exists according to the lookup. The rule parameter is
+// passed to the lookup. A lookup error is not a violation of the rule, and
+// the validation results in a LookupFailed error carrying it instead.
+func RegisterLookupRule(tag string, lookup Lookup) {
+	RegisterContextRule(tag, func(ctx context.Context, fl validator.FieldLevel) bool {
+		exists, err := lookup.Exists(ctx, fl.Param(), fl.Field().Interface())
+		if err != nil {
+			failure, ok := ctx.Value(lookupFailureKey{}).(*lookupFailure)
+			if !ok {
+				return false
+			}
+
+			if failure.err == nil {
+				failure.err = err
+			}
+			return true
+		}
+
+		return exists
+	}, false)
+}
+
+// lookupFailureKey is the context key of the lookup failure of validations.
+type lookupFailureKey struct{}
+
+// lookupFailure records the first lookup error of a validation.
+type lookupFailure struct {
+	err error
+}
+
+// RegisterStructRule registers a struct level validation function for given
+// types, used for cross-field validations. Violations must be reported using
+// StructLevel.ReportError, so that they are added to the error bag. Like
+// RegisterRule, it must be called before use.
+func RegisterStructRule(fn validator.StructLevelFuncCtx, types ...interface{}) {
+	register(func(v *validator.Validate) error {
+		v.RegisterStructValidationCtx(fn, types...)
+		return nil
+	})
+}
+
+// register applies the rule to the validator right away, so that the
+// validator is not modified lazily while other goroutines validate with it.
+func register(r registration) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+
+	rules = append(rules, r)
+	applyRules(orca.GetValidator())
+}
+
+func tagRule(tag string, fn validator.Func, callEvenIfNull bool) registration {
+	return func(v *validator.Validate) error {
+		return v.RegisterValidation(tag, fn, callEvenIfNull)
+	}
+}
+
+// getValidator returns the validator with the registered rules applied. The
+// rules are only applied here for the first use of the validator, i.e. the
+// built-in rules.
+func getValidator() *validator.Validate {
+	validate := orca.GetValidator()
+	if atomic.LoadUint32(&ready) == 1 {
+		return validate
+	}
+
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+
+	applyRules(validate)
+	atomic.StoreUint32(&ready, 1)
+
+	return validate
+}
+
+// applyRules applies the registered rules which are not applied yet to the
+// validator. It must be called with rulesMu held.
+func applyRules(validate *validator.Validate) {
+	if validate != configured {
+		configured, applied = validate, 0
+	}
+
+	for ; applied < len(rules); applied++ {
+		shark.PanicIfError(rules[applied](validate))
+	}
+}
+
+// isIBAN validates the International Bank Account Number, using its mod-97
+// check digits.
+func isIBAN(fl validator.FieldLevel) bool {
+	iban := strings.ToUpper(strings.ReplaceAll(fl.Field().String(), " ", ""))
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	var digits strings.Builder
+	for _, c := range iban[4:] + iban[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case c >= 'A' && c <= 'Z':
+			digits.WriteString(fmt.Sprint(c - 'A' + 10))
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && n.Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// isIranianNationalCode validates the 10 digits Iranian national code using
+// its check digit.
+func isIranianNationalCode(fl validator.FieldLevel) bool {
+	code := fl.Field().String()
+	if len(code) != 10 || strings.Count(code, code[:1]) == 10 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 9; i++ {
+		if code[i] < '0' || code[i] > '9' {
+			return false
+		}
+		sum += int(code[i]-'0') * (10 - i)
+	}
+
+	if code[9] < '0' || code[9] > '9' {
+		return false
+	}
+
+	check, remainder := int(code[9]-'0'), sum%11
+	if remainder < 2 {
+		return check == remainder
+	}
+
+	return check == 11-remainder
+}
+
+// requiredIf validates that the field has a value when another field of the
+// struct equals to a value, e.g. `required_if=Type company`. A malformed param
+// is a violation of the rule.
+func requiredIf(fl validator.FieldLevel) bool {
+	params := strings.Fields(fl.Param())
+	if len(params) != 2 {
+		return false
+	}
+
+	parent := reflect.Indirect(fl.Parent())
+	if parent.Kind() != reflect.Struct {
+		return true
+	}
+
+	other := reflect.Indirect(parent.FieldByName(params[0]))
+	if !other.IsValid() || fmt.Sprint(other.Interface()) != params[1] {
+		return true
+	}
+
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return !field.IsNil()
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return field.Len() > 0
+	default:
+		return field.IsValid() && !field.IsZero()
+	}
+}
@@ -0,0 +1,105 @@
+package pantopoda
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// lookupFunc adapts a function to the Lookup interface.
+type lookupFunc func(param string, value interface{}) (bool, error)
+
+func (f lookupFunc) Exists(ctx context.Context, param string, value interface{}) (bool, error) {
+	return f(param, value)
+}
+
+type contextKey struct{}
+
+func init() {
+	RegisterContextRule("test_context", func(ctx context.Context, fl validator.FieldLevel) bool {
+		return fl.Field().String() == ctx.Value(contextKey{})
+	}, false)
+
+	RegisterLookupRule("test_exists", lookupFunc(func(param string, value interface{}) (bool, error) {
+		if value == "broken" {
+			return false, errors.New("lookup failed")
+		}
+
+		return param == "users.email" && value == "a@example.com", nil
+	}))
+}
+
+// assertRules validates the value and checks the violated rules.
+func assertRules(t *testing.T, ctx context.Context, value interface{}, want ...string) {
+	t.Helper()
+
+	validationError := ValidateContext(ctx, value)
+	if len(want) == 0 && validationError.Failed() {
+		t.Errorf("expected %+v to be valid, got %s", value, validationError)
+		return
+	}
+
+	got := make([]string, 0, len(validationError.Fields()))
+	for _, field := range validationError.Fields() {
+		got = append(got, field.Field+":"+field.Rule)
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %+v to violate %v, got %v", value, want, got)
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %+v to violate %v, got %v", value, want, got)
+			return
+		}
+	}
+}
+
+func TestBuiltInRules(t *testing.T) {
+	type account struct {
+		IBAN string `json:"iban" validate:"iban"`
+	}
+	type person struct {
+		NationalCode string `json:"national_code" validate:"ir_national_code"`
+	}
+	type customer struct {
+		Type    string `json:"type"`
+		Company string `json:"company" validate:"required_if=Type company"`
+	}
+	type malformed struct {
+		Type    string `json:"type"`
+		Company string `json:"company" validate:"required_if=Type"`
+	}
+
+	ctx := context.Background()
+	assertRules(t, ctx, account{IBAN: "GB82 WEST 1234 5698 7654 32"})
+	assertRules(t, ctx, account{IBAN: "GB83WEST12345698765432"}, "iban:iban")
+	assertRules(t, ctx, account{IBAN: "GB82"}, "iban:iban")
+	assertRules(t, ctx, person{NationalCode: "0012345679"})
+	assertRules(t, ctx, person{NationalCode: "0012345678"}, "national_code:ir_national_code")
+	assertRules(t, ctx, person{NationalCode: "1111111111"}, "national_code:ir_national_code")
+	assertRules(t, ctx, person{NationalCode: "12345"}, "national_code:ir_national_code")
+	assertRules(t, ctx, customer{Type: "company", Company: "Kamva"})
+	assertRules(t, ctx, customer{Type: "person"})
+	assertRules(t, ctx, customer{Type: "company"}, "company:required_if")
+	assertRules(t, ctx, malformed{Type: "company", Company: "Kamva"}, "company:required_if")
+}
+
+func TestContextRules(t *testing.T) {
+	type request struct {
+		Token string `json:"token" validate:"test_context"`
+		Email string `json:"email" validate:"omitempty,test_exists=users.email"`
+	}
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "secret")
+	assertRules(t, ctx, request{Token: "secret", Email: "a@example.com"})
+	assertRules(t, ctx, request{Token: "other"}, "token:test_context")
+	assertRules(t, ctx, request{Token: "secret", Email: "b@example.com"}, "email:test_exists")
+
+	validationError := ValidateContext(ctx, request{Token: "secret", Email: "broken"})
+	if validationError.ErrorType != LookupFailed || validationError.Unwrap() == nil {
+		t.Errorf("expected a lookup failure, got %s", validationError)
+	}
+}
@@ -0,0 +1,264 @@
+// Package schema implements validation of json documents against JSON Schema.
+//
+// It supports a subset of draft 2020-12 which covers the common needs of
+// request and response validation: `type`, `enum`, `const`, `properties`,
+// `required`, `additionalProperties`, `minProperties`, `maxProperties`,
+// `items`, `prefixItems`, `minItems`, `maxItems`, `uniqueItems`,
+// `minLength`, `maxLength`, `pattern`, `format`, `minimum`, `maximum`,
+// `exclusiveMinimum`, `exclusiveMaximum`, `multipleOf`, `allOf`, `anyOf`,
+// `oneOf`, `not`, and `$ref` to the document itself or its `$defs`.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Schema is a JSON Schema, either parsed by Parse or built in code. Schemas
+// built in code are compiled by Compile, or on their first validation.
+type Schema struct {
+	Ref  string             `json:"$ref"`
+	Defs map[string]*Schema `json:"$defs"`
+
+	Type  Types            `json:"type"`
+	Enum  []interface{}    `json:"enum"`
+	Const *json.RawMessage `json:"const"`
+	AllOf []*Schema        `json:"allOf"`
+	AnyOf []*Schema        `json:"anyOf"`
+	OneOf []*Schema        `json:"oneOf"`
+	Not   *Schema          `json:"not"`
+
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *Schema            `json:"additionalProperties"`
+	MinProperties        *int               `json:"minProperties"`
+	MaxProperties        *int               `json:"maxProperties"`
+
+	Items       *Schema   `json:"items"`
+	PrefixItems []*Schema `json:"prefixItems"`
+	MinItems    *int      `json:"minItems"`
+	MaxItems    *int      `json:"maxItems"`
+	UniqueItems bool      `json:"uniqueItems"`
+
+	MinLength *int   `json:"minLength"`
+	MaxLength *int   `json:"maxLength"`
+	Pattern   string `json:"pattern"`
+	Format    string `json:"format"`
+
+	Minimum          *float64 `json:"minimum"`
+	Maximum          *float64 `json:"maximum"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum"`
+	MultipleOf       *float64 `json:"multipleOf"`
+
+	// boolean is set for the `true` and `false` schemas.
+	boolean *bool
+	pattern *regexp.Regexp
+	root    *Schema
+
+	// once compiles the schema built in code, and err is the error of
+	// compiling it.
+	once sync.Once
+	err  error
+}
+
+// Types is the list of types allowed by the `type` keyword.
+type Types []string
+
+// UnmarshalJSON decodes the `type` keyword, which is either a type name or a
+// list of type names.
+func (t *Types) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*t = Types{name}
+		return nil
+	}
+
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return err
+	}
+
+	*t = names
+	return nil
+}
+
+// UnmarshalJSON decodes the schema, which is either a boolean or an object.
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	var boolean bool
+	if err := json.Unmarshal(b, &boolean); err == nil {
+		s.boolean = &boolean
+		return nil
+	}
+
+	type schema Schema
+	return json.Unmarshal(b, (*schema)(s))
+}
+
+// Parse parses the JSON Schema document.
+func Parse(document []byte) (*Schema, error) {
+	s := new(Schema)
+	if err := json.Unmarshal(document, s); err != nil {
+		return nil, err
+	}
+
+	if err := s.compile(s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// MustParse is like Parse but panics if the document cannot be parsed.
+func MustParse(document []byte) *Schema {
+	s, err := Parse(document)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// Compile compiles the schema built in code, i.e. compiles its patterns and
+// resolves its references, unless it is compiled already by Parse or as a
+// subschema of another schema. It returns the error of compiling it, which
+// is also returned by Validate, so that schemas built in code are checked
+// before use.
+func (s *Schema) Compile() error {
+	s.once.Do(func() {
+		if s.root == nil {
+			s.err = s.compile(s)
+		}
+	})
+
+	return s.err
+}
+
+// compile links the schema and its subschemas to the root schema, compiles
+// their patterns, and rejects cyclic references.
+func (s *Schema) compile(root *Schema) error {
+	if s == nil {
+		return nil
+	}
+
+	s.root = root
+
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %s", s.Pattern, err)
+		}
+		s.pattern = pattern
+	}
+
+	if s.Ref != "" {
+		if _, err := s.resolve(); err != nil {
+			return err
+		}
+	}
+
+	for _, child := range s.children() {
+		if err := child.compile(root); err != nil {
+			return err
+		}
+	}
+
+	// The references are checked once all schemas are linked to the root.
+	if s == root {
+		return s.checkCycles(make(map[*Schema]bool))
+	}
+
+	return nil
+}
+
+// children returns the subschemas of the schema.
+func (s *Schema) children() []*Schema {
+	children := []*Schema{s.Not, s.AdditionalProperties, s.Items}
+	children = append(children, s.AllOf...)
+	children = append(children, s.AnyOf...)
+	children = append(children, s.OneOf...)
+	children = append(children, s.PrefixItems...)
+	for _, child := range s.Properties {
+		children = append(children, child)
+	}
+	for _, child := range s.Defs {
+		children = append(children, child)
+	}
+
+	return children
+}
+
+// checkCycles rejects the schemas which apply themselves to the same value
+// through `$ref`, `allOf`, `anyOf`, `oneOf` and `not`, e.g. `{"$ref": "#"}`,
+// as validating them never ends. References through `properties` and
+// `items` are fine, as they apply to the nested values.
+func (s *Schema) checkCycles(checked map[*Schema]bool) error {
+	if s == nil || checked[s] {
+		return nil
+	}
+	checked[s] = true
+
+	if s.appliesToItself(s, make(map[*Schema]bool)) {
+		return fmt.Errorf("schema refers to itself without validating a nested value")
+	}
+
+	for _, child := range s.children() {
+		if err := child.checkCycles(checked); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appliesToItself checks that the target schema is applied to the same value
+// as the schema, while validating it.
+func (s *Schema) appliesToItself(target *Schema, visited map[*Schema]bool) bool {
+	applied := append([]*Schema{s.Not}, s.AllOf...)
+	applied = append(applied, s.AnyOf...)
+	applied = append(applied, s.OneOf...)
+	if s.Ref != "" {
+		if ref, err := s.resolve(); err == nil {
+			applied = append(applied, ref)
+		}
+	}
+
+	for _, schema := range applied {
+		if schema == nil || visited[schema] {
+			continue
+		}
+		if schema == target {
+			return true
+		}
+
+		visited[schema] = true
+		if schema.appliesToItself(target, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolve returns the schema referenced by `$ref`. Only references to the
+// root schema and its `$defs` are supported.
+func (s *Schema) resolve() (*Schema, error) {
+	root := s.root
+	if root == nil {
+		root = s
+	}
+
+	if s.Ref == "#" {
+		return root, nil
+	}
+
+	name := strings.TrimPrefix(s.Ref, "#/$defs/")
+	if name == s.Ref || root.Defs[name] == nil {
+		return nil, fmt.Errorf("unsupported reference %q", s.Ref)
+	}
+
+	return root.Defs[name], nil
+}
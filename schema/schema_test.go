@@ -0,0 +1,178 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+// keywords returns the violated keywords of the errors.
+func keywords(errs []Error) string {
+	names := make([]string, len(errs))
+	for i, err := range errs {
+		names[i] = err.Path + ":" + err.Keyword
+	}
+
+	return strings.Join(names, ",")
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		schema   string
+		document string
+		want     string
+	}{
+		{`true`, `1`, ""},
+		{`false`, `1`, ":false"},
+
+		{`{"type":"string"}`, `"a"`, ""},
+		{`{"type":"string"}`, `1`, ":type"},
+		{`{"type":["string","null"]}`, `null`, ""},
+		{`{"type":"integer"}`, `1.0`, ""},
+		{`{"type":"integer"}`, `1.5`, ":type"},
+		{`{"type":"number"}`, `1.5`, ""},
+		{`{"type":"boolean"}`, `true`, ""},
+		{`{"type":"array"}`, `{}`, ":type"},
+		{`{"type":"object"}`, `{}`, ""},
+
+		{`{"enum":["a",1]}`, `1`, ""},
+		{`{"enum":["a",1]}`, `"b"`, ":enum"},
+		{`{"const":{"a":1}}`, `{"a":1.0}`, ""},
+		{`{"const":{"a":1}}`, `{"a":2}`, ":const"},
+
+		{`{"properties":{"a":{"type":"string"}},"required":["a","b"]}`, `{"a":1}`, "b:required,a:type"},
+		{`{"additionalProperties":false,"properties":{"a":true}}`, `{"a":1,"b":2}`, "b:false"},
+		{`{"additionalProperties":{"type":"number"}}`, `{"a":1,"b":"x"}`, "b:type"},
+		{`{"minProperties":2}`, `{"a":1}`, ":minProperties"},
+		{`{"maxProperties":1}`, `{"a":1,"b":2}`, ":maxProperties"},
+
+		{`{"items":{"type":"number"}}`, `[1,"a"]`, "[1]:type"},
+		{`{"prefixItems":[{"type":"string"}],"items":{"type":"number"}}`, `["a",1,"b"]`, "[2]:type"},
+		{`{"minItems":2}`, `[1]`, ":minItems"},
+		{`{"maxItems":1}`, `[1,2]`, ":maxItems"},
+		{`{"uniqueItems":true}`, `[1,2,1]`, ":uniqueItems"},
+		{`{"uniqueItems":true}`, `[1,2]`, ""},
+
+		{`{"minLength":2}`, `"é"`, ":minLength"},
+		{`{"maxLength":2}`, `"abc"`, ":maxLength"},
+		{`{"pattern":"^a+$"}`, `"aa"`, ""},
+		{`{"pattern":"^a+$"}`, `"ab"`, ":pattern"},
+		{`{"format":"email"}`, `"a@example.com"`, ""},
+		{`{"format":"email"}`, `"a"`, ":format"},
+		{`{"format":"date-time"}`, `"2020-01-02T03:04:05Z"`, ""},
+		{`{"format":"date"}`, `"2020-13-01"`, ":format"},
+		{`{"format":"uri"}`, `"/relative"`, ":format"},
+		{`{"format":"uuid"}`, `"123e4567-e89b-12d3-a456-426614174000"`, ""},
+		{`{"format":"ipv4"}`, `"::1"`, ":format"},
+		{`{"format":"ipv6"}`, `"::1"`, ""},
+		{`{"format":"unknown"}`, `"x"`, ""},
+
+		{`{"minimum":1}`, `1`, ""},
+		{`{"minimum":1}`, `0`, ":minimum"},
+		{`{"maximum":1}`, `2`, ":maximum"},
+		{`{"exclusiveMinimum":1}`, `1`, ":exclusiveMinimum"},
+		{`{"exclusiveMaximum":1}`, `1`, ":exclusiveMaximum"},
+		{`{"multipleOf":0.1}`, `0.3`, ""},
+		{`{"multipleOf":2}`, `3`, ":multipleOf"},
+
+		{`{"allOf":[{"minimum":1},{"maximum":2}]}`, `3`, ":maximum"},
+		{`{"anyOf":[{"type":"string"},{"type":"number"}]}`, `true`, ":anyOf"},
+		{`{"oneOf":[{"minimum":1},{"minimum":2}]}`, `3`, ":oneOf"},
+		{`{"oneOf":[{"minimum":1},{"minimum":2}]}`, `1`, ""},
+		{`{"not":{"type":"string"}}`, `"a"`, ":not"},
+
+		{`{"$defs":{"id":{"type":"integer"}},"properties":{"id":{"$ref":"#/$defs/id"}}}`, `{"id":"a"}`, "id:type"},
+		{`{"properties":{"child":{"$ref":"#"}},"required":["name"]}`, `{"name":"a","child":{}}`, "child.name:required"},
+	}
+
+	for _, test := range tests {
+		s, err := Parse([]byte(test.schema))
+		if err != nil {
+			t.Errorf("%s: %v", test.schema, err)
+			continue
+		}
+
+		errs, err := s.Validate([]byte(test.document))
+		if err != nil {
+			t.Errorf("%s: %v", test.schema, err)
+			continue
+		}
+
+		if got := keywords(errs); got != test.want {
+			t.Errorf("expected %s validated by %s to violate %q, got %q", test.document, test.schema, test.want, got)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		`{"$ref":"#"}`,
+		`{"$defs":{"a":{"$ref":"#/$defs/a"}},"$ref":"#/$defs/a"}`,
+		`{"$defs":{"a":{"allOf":[{"$ref":"#/$defs/b"}]},"b":{"anyOf":[{"$ref":"#/$defs/a"}]}}}`,
+		`{"not":{"$ref":"#"}}`,
+		`{"$ref":"#/$defs/missing"}`,
+		`{"$ref":"http://example.com/schema"}`,
+		`{"pattern":"("}`,
+		`{"type":1}`,
+	}
+
+	for _, test := range tests {
+		if _, err := Parse([]byte(test)); err == nil {
+			t.Errorf("expected %s to be rejected", test)
+		}
+	}
+}
+
+func TestValidateInvalidDocument(t *testing.T) {
+	s := MustParse([]byte(`{"type":"object"}`))
+
+	tests := []string{``, `{`, `{} {}`, `{}]`, `{}x`}
+	for _, test := range tests {
+		if _, err := s.Validate([]byte(test)); err == nil {
+			t.Errorf("expected %q to be rejected", test)
+		}
+	}
+
+	if _, err := s.Validate([]byte(" {} \n")); err != nil {
+		t.Errorf("expected the surrounding whitespace to be accepted, got %v", err)
+	}
+}
+
+func TestSchemaBuiltInCode(t *testing.T) {
+	limit := 2
+	s := &Schema{
+		Defs: map[string]*Schema{"name": {Type: Types{"string"}, Pattern: "^a", MaxLength: &limit}},
+		Properties: map[string]*Schema{
+			"name":   {Ref: "#/$defs/name"},
+			"parent": {Ref: "#"},
+		},
+	}
+
+	errs, err := s.Validate([]byte(`{"name":"bcd","parent":{"name":"ab"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keywords(errs); got != "name:maxLength,name:pattern" {
+		t.Errorf("unexpected violations %q", got)
+	}
+
+	if errs := s.ValidateValue(map[string]interface{}{"name": "a"}); len(errs) != 0 {
+		t.Errorf("unexpected violations %v", errs)
+	}
+
+	invalid := []*Schema{
+		{Pattern: "("},
+		{Ref: "#/$defs/missing"},
+		{Ref: "#"},
+	}
+	for _, s := range invalid {
+		if err := s.Compile(); err == nil {
+			t.Errorf("expected %+v not to compile", s)
+		}
+		if _, err := s.Validate([]byte(`"a"`)); err == nil {
+			t.Errorf("expected the validation by %+v to fail", s)
+		}
+		if errs := s.ValidateValue("a"); len(errs) != 1 || errs[0].Keyword != "schema" {
+			t.Errorf("expected the schema violation, got %v", errs)
+		}
+	}
+}
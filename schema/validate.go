@@ -0,0 +1,406 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Error is a violation of a schema keyword by a json value.
+type Error struct {
+	// Path is the path of the violating value in the document, e.g.
+	// `items[2].price`. It is empty for the document itself.
+	Path string
+
+	// Keyword is the violated schema keyword, e.g. `minLength`.
+	Keyword string
+
+	// Param is the value of the violated keyword, e.g. `3` for
+	// `"minLength": 3`.
+	Param string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: violates %s %s", e.Path, e.Keyword, e.Param)
+}
+
+// Validate validates the json document against the schema, and returns the
+// violations. An error is returned if the document is not a single valid
+// json value, or the schema cannot be compiled.
+func (s *Schema) Validate(document []byte) ([]Error, error) {
+	if err := s.Compile(); err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after the json value")
+	}
+
+	return s.validate("", normalize(value)), nil
+}
+
+// ValidateValue validates the value decoded by encoding/json against the
+// schema, and returns the violations. When the schema cannot be compiled,
+// the value is reported to violate the `schema` keyword, with the error as
+// the param.
+func (s *Schema) ValidateValue(value interface{}) []Error {
+	if err := s.Compile(); err != nil {
+		return []Error{{Keyword: "schema", Param: err.Error()}}
+	}
+
+	return s.validate("", normalize(value))
+}
+
+func (s *Schema) validate(path string, value interface{}) []Error {
+	if s == nil {
+		return nil
+	}
+
+	if s.boolean != nil {
+		if *s.boolean {
+			return nil
+		}
+		return []Error{{Path: path, Keyword: "false"}}
+	}
+
+	if s.Ref != "" {
+		ref, err := s.resolve()
+		if err != nil {
+			return []Error{{Path: path, Keyword: "$ref", Param: s.Ref}}
+		}
+		if errs := ref.validate(path, value); len(errs) > 0 {
+			return errs
+		}
+	}
+
+	if len(s.Type) > 0 && !s.matchesType(value) {
+		return []Error{{Path: path, Keyword: "type", Param: fmt.Sprint(s.Type)}}
+	}
+
+	errs := s.validateGeneric(path, value)
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		errs = append(errs, s.validateObject(path, v)...)
+	case []interface{}:
+		errs = append(errs, s.validateArray(path, v)...)
+	case string:
+		errs = append(errs, s.validateString(path, v)...)
+	case float64:
+		errs = append(errs, s.validateNumber(path, v)...)
+	}
+
+	return errs
+}
+
+func (s *Schema) matchesType(value interface{}) bool {
+	for _, t := range s.Type {
+		switch v := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case float64:
+			if t == "number" || (t == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (s *Schema) validateGeneric(path string, value interface{}) []Error {
+	errs := make([]Error, 0)
+
+	if s.Const != nil {
+		var constant interface{}
+		if err := json.Unmarshal(*s.Const, &constant); err == nil && !reflect.DeepEqual(normalize(constant), value) {
+			errs = append(errs, Error{Path: path, Keyword: "const", Param: string(*s.Const)})
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, item := range s.Enum {
+			if reflect.DeepEqual(normalize(item), value) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			errs = append(errs, Error{Path: path, Keyword: "enum", Param: joinValues(s.Enum)})
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		errs = append(errs, sub.validate(path, value)...)
+	}
+
+	if len(s.AnyOf) > 0 && s.countValid(s.AnyOf, path, value) == 0 {
+		errs = append(errs, Error{Path: path, Keyword: "anyOf"})
+	}
+
+	if len(s.OneOf) > 0 && s.countValid(s.OneOf, path, value) != 1 {
+		errs = append(errs, Error{Path: path, Keyword: "oneOf"})
+	}
+
+	if s.Not != nil && len(s.Not.validate(path, value)) == 0 {
+		errs = append(errs, Error{Path: path, Keyword: "not"})
+	}
+
+	return errs
+}
+
+func (s *Schema) countValid(schemas []*Schema, path string, value interface{}) int {
+	valid := 0
+	for _, sub := range schemas {
+		if len(sub.validate(path, value)) == 0 {
+			valid++
+		}
+	}
+
+	return valid
+}
+
+func (s *Schema) validateObject(path string, object map[string]interface{}) []Error {
+	errs := make([]Error, 0)
+
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			errs = append(errs, Error{Path: join(path, name), Keyword: "required"})
+		}
+	}
+
+	if s.MinProperties != nil && len(object) < *s.MinProperties {
+		errs = append(errs, Error{Path: path, Keyword: "minProperties", Param: strconv.Itoa(*s.MinProperties)})
+	}
+
+	if s.MaxProperties != nil && len(object) > *s.MaxProperties {
+		errs = append(errs, Error{Path: path, Keyword: "maxProperties", Param: strconv.Itoa(*s.MaxProperties)})
+	}
+
+	for _, name := range sortedKeys(object) {
+		if property, ok := s.Properties[name]; ok {
+			errs = append(errs, property.validate(join(path, name), object[name])...)
+		} else if s.AdditionalProperties != nil {
+			errs = append(errs, s.AdditionalProperties.validate(join(path, name), object[name])...)
+		}
+	}
+
+	return errs
+}
+
+func (s *Schema) validateArray(path string, array []interface{}) []Error {
+	errs := make([]Error, 0)
+
+	if s.MinItems != nil && len(array) < *s.MinItems {
+		errs = append(errs, Error{Path: path, Keyword: "minItems", Param: strconv.Itoa(*s.MinItems)})
+	}
+
+	if s.MaxItems != nil && len(array) > *s.MaxItems {
+		errs = append(errs, Error{Path: path, Keyword: "maxItems", Param: strconv.Itoa(*s.MaxItems)})
+	}
+
+	if s.UniqueItems {
+	unique:
+		for i := range array {
+			for j := i + 1; j < len(array); j++ {
+				if reflect.DeepEqual(array[i], array[j]) {
+					errs = append(errs, Error{Path: path, Keyword: "uniqueItems"})
+					break unique
+				}
+			}
+		}
+	}
+
+	for i, item := range array {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+
+		if i < len(s.PrefixItems) {
+			errs = append(errs, s.PrefixItems[i].validate(itemPath, item)...)
+		} else if s.Items != nil {
+			errs = append(errs, s.Items.validate(itemPath, item)...)
+		}
+	}
+
+	return errs
+}
+
+func (s *Schema) validateString(path string, str string) []Error {
+	errs := make([]Error, 0)
+	length := utf8.RuneCountInString(str)
+
+	if s.MinLength != nil && length < *s.MinLength {
+		errs = append(errs, Error{Path: path, Keyword: "minLength", Param: strconv.Itoa(*s.MinLength)})
+	}
+
+	if s.MaxLength != nil && length > *s.MaxLength {
+		errs = append(errs, Error{Path: path, Keyword: "maxLength", Param: strconv.Itoa(*s.MaxLength)})
+	}
+
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		errs = append(errs, Error{Path: path, Keyword: "pattern", Param: s.Pattern})
+	}
+
+	if s.Format != "" && !validFormat(s.Format, str) {
+		errs = append(errs, Error{Path: path, Keyword: "format", Param: s.Format})
+	}
+
+	return errs
+}
+
+func (s *Schema) validateNumber(path string, number float64) []Error {
+	errs := make([]Error, 0)
+	limits := []struct {
+		keyword  string
+		limit    *float64
+		violated func(limit float64) bool
+	}{
+		{"minimum", s.Minimum, func(limit float64) bool { return number < limit }},
+		{"maximum", s.Maximum, func(limit float64) bool { return number > limit }},
+		{"exclusiveMinimum", s.ExclusiveMinimum, func(limit float64) bool { return number <= limit }},
+		{"exclusiveMaximum", s.ExclusiveMaximum, func(limit float64) bool { return number >= limit }},
+		{"multipleOf", s.MultipleOf, func(limit float64) bool {
+			quotient := number / limit
+			return math.Abs(quotient-math.Round(quotient)) > 1e-9
+		}},
+	}
+
+	for _, l := range limits {
+		if l.limit != nil && l.violated(*l.limit) {
+			errs = append(errs, Error{Path: path, Keyword: l.keyword, Param: strconv.FormatFloat(*l.limit, 'f', -1, 64)})
+		}
+	}
+
+	return errs
+}
+
+// validFormat checks the string against the format. Unknown formats are
+// considered as annotations and always pass.
+func validFormat(format string, str string) bool {
+	var err error
+
+	switch format {
+	case "email":
+		_, err = mail.ParseAddress(str)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, str)
+	case "date":
+		_, err = time.Parse("2006-01-02", str)
+	case "uri":
+		var u *url.URL
+		if u, err = url.Parse(str); err == nil && !u.IsAbs() {
+			return false
+		}
+	case "uuid":
+		return uuidPattern.MatchString(str)
+	case "ipv4":
+		ip := net.ParseIP(str)
+		return ip != nil && ip.To4() != nil
+	case "ipv6":
+		ip := net.ParseIP(str)
+		return ip != nil && ip.To4() == nil
+	}
+
+	return err == nil
+}
+
+// normalize converts the numbers of decoded json value into float64, so that
+// values can be compared regardless of how they are decoded.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			normalized[key] = normalize(item)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			normalized[i] = normalize(item)
+		}
+		return normalized
+	default:
+		return v
+	}
+}
+
+// join appends the property name to the path.
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// sortedKeys returns the keys of object in sorted order, so that violations
+// are reported deterministically.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// joinValues joins the json representation of values with comma.
+func joinValues(values []interface{}) string {
+	strs := make([]string, len(values))
+	for i, value := range values {
+		b, _ := json.Marshal(value)
+		strs[i] = string(b)
+	}
+
+	return strings.Join(strs, ",")
+}
@@ -0,0 +1,74 @@
+package pantopoda
+
+import (
+	"github.com/Kamva/pantopoda/schema"
+	"github.com/Kamva/shark"
+)
+
+// schemaRules maps the JSON Schema keywords into their equivalent validation
+// rules, so that their messages can be translated alike.
+var schemaRules = map[string]string{
+	"minLength":        "min",
+	"maxLength":        "max",
+	"minItems":         "min",
+	"maxItems":         "max",
+	"minimum":          "gte",
+	"maximum":          "lte",
+	"exclusiveMinimum": "gt",
+	"exclusiveMaximum": "lt",
+	"enum":             "oneof",
+	"const":            "eq",
+	"uniqueItems":      "unique",
+}
+
+// SchemaRoot is the field name used in the error bag for violations of the
+// document itself, rather than one of its fields.
+const SchemaRoot = "body"
+
+// ValidateSchema validates the json document against the JSON Schema and
+// returns the violations as validation error, in the same format Validate
+// does. The document which is not valid json results in a BadRequest error.
+// It panics if the schema cannot be compiled, e.g. a schema built in code
+// with an invalid pattern, which schema.Compile reports beforehand.
+func ValidateSchema(s *schema.Schema, document []byte) ValidationError {
+	shark.PanicIfError(s.Compile())
+
+	validationError := ValidationError{}
+
+	errs, err := s.Validate(document)
+	if err != nil {
+		validationError.ErrorType = BadRequest
+		return validationError
+	}
+
+	if len(errs) == 0 {
+		return validationError
+	}
+
+	errorBag := shark.NewErrorBag()
+	for _, err := range errs {
+		fieldError := FieldError{
+			Field: err.Path,
+			Rule:  err.Keyword,
+			Param: err.Param,
+		}
+		if fieldError.Field == "" {
+			fieldError.Field = SchemaRoot
+		}
+		if rule, ok := schemaRules[err.Keyword]; ok {
+			fieldError.Rule = rule
+		}
+		if err.Keyword == "format" {
+			fieldError.Rule = err.Param
+		}
+		fieldError.Key = fieldError.Rule
+
+		errorBag.Append(fieldError.Field, fieldError.Key)
+		validationError.addField(fieldError)
+	}
+
+	validationError.ErrorType = RuleViolation
+	validationError.ErrorBag = errorBag
+
+	return validationError
+}
@@ -0,0 +1,219 @@
+package pantopoda
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PinError is returned when the certificate chain of a host does not contain
+// any of the public keys pinned for it.
+type PinError struct {
+	Host string
+
+	// Pins are the pins of the certificates presented by the host.
+	Pins []string
+}
+
+func (e PinError) Error() string {
+	return fmt.Sprintf("pantopoda: certificate of %s does not match the pinned keys, got %s", e.Host, strings.Join(e.Pins, ", "))
+}
+
+// SPKIPin returns the pin of the certificate, i.e. the base64 of the SHA-256
+// of its subject public key info, in the form of `sha256/<base64>`.
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// WithClientCertificate sends the client certificate loaded from the PEM
+// files of the certificate and its key to servers requiring mutual TLS. The
+// files are watched for changes, and loaded again when they are modified, so
+// that certificates are rotated without restarting the process.
+func WithClientCertificate(certFile string, keyFile string) Option {
+	return func(c *Pantopoda) {
+		files := &certificateFiles{certFile: certFile, keyFile: keyFile}
+		c.tlsConfig().GetClientCertificate = files.certificate
+	}
+}
+
+// WithClientCertificatePEM sends the client certificate, given as the PEM
+// blocks of the certificate and its key, to servers requiring mutual TLS.
+func WithClientCertificatePEM(certPEM []byte, keyPEM []byte) Option {
+	return func(c *Pantopoda) {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		c.tlsConfig().GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if err != nil {
+				return nil, err
+			}
+			return &cert, nil
+		}
+	}
+}
+
+// WithRootCAs verifies the certificates of servers using the pool of root
+// certificate authorities, instead of the root pool of the system.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *Pantopoda) {
+		c.tlsConfig().RootCAs = pool
+	}
+}
+
+// WithPins pins the public keys of the host, e.g. `api.bank.com`, which are
+// given in the form returned by SPKIPin. Connections to the host fail with
+// PinError unless its verified certificate chain contains one of the keys.
+// When the chain is not verified, e.g. with InsecureSkipVerify, only the key
+// of the leaf certificate is matched. Pin the keys of backup certificates
+// too, to be able to replace the certificate of the host. The host is
+// matched against the TLS server name of connections, which is not sent for
+// IP addresses, so they cannot be pinned.
+func WithPins(host string, pins ...string) Option {
+	return func(c *Pantopoda) {
+		if c.pins == nil {
+			c.pins = make(map[string]map[string]bool)
+			c.tlsConfig().VerifyConnection = c.verifyPins
+		}
+
+		host = strings.ToLower(host)
+		if c.pins[host] == nil {
+			c.pins[host] = make(map[string]bool)
+		}
+		for _, pin := range pins {
+			if !strings.HasPrefix(pin, "sha256/") {
+				pin = "sha256/" + pin
+			}
+			c.pins[host][pin] = true
+		}
+	}
+}
+
+// WithTLSVersion restricts the connections to the TLS versions from the min
+// version, e.g. tls.VersionTLS12, and to the cipher suites, if they are
+// given. Cipher suites are not configurable in TLS 1.3.
+func WithTLSVersion(min uint16, cipherSuites ...uint16) Option {
+	return func(c *Pantopoda) {
+		c.tlsConfig().MinVersion = min
+		if len(cipherSuites) > 0 {
+			c.tlsConfig().CipherSuites = cipherSuites
+		}
+	}
+}
+
+// tlsConfig returns the TLS config of the transport of the client.
+func (c *Pantopoda) tlsConfig() *tls.Config {
+	if c.transport.TLSClientConfig == nil {
+		c.transport.TLSClientConfig = &tls.Config{}
+	}
+
+	return c.transport.TLSClientConfig
+}
+
+// verifyPins verifies the certificate chain of the connection against the
+// pins of its host, if there are any.
+func (c *Pantopoda) verifyPins(state tls.ConnectionState) error {
+	host := strings.ToLower(state.ServerName)
+	pins, ok := c.pins[host]
+	if !ok {
+		return nil
+	}
+
+	// The chains are not verified when the verification is skipped, or done
+	// by a custom verifier. The certificates sent by the peer besides its own
+	// are then untrusted, as any public certificate can be appended to them,
+	// so only the leaf certificate is matched.
+	chains := state.VerifiedChains
+	if len(chains) == 0 && len(state.PeerCertificates) > 0 {
+		chains = [][]*x509.Certificate{state.PeerCertificates[:1]}
+	}
+
+	for _, chain := range chains {
+		for _, cert := range chain {
+			if pins[SPKIPin(cert)] {
+				return nil
+			}
+		}
+	}
+
+	got := make([]string, len(state.PeerCertificates))
+	for i, cert := range state.PeerCertificates {
+		got[i] = SPKIPin(cert)
+	}
+
+	return PinError{Host: host, Pins: got}
+}
+
+// certificateCheckInterval is the minimum interval between checking the
+// certificate files for changes.
+const certificateCheckInterval = time.Second
+
+// certificateFiles loads the client certificate from the files, when their
+// modification times change.
+type certificateFiles struct {
+	certFile string
+	keyFile  string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func (f *certificateFiles) certificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if f.cert != nil && now.Sub(f.checkedAt) < certificateCheckInterval {
+		return f.cert, nil
+	}
+	f.checkedAt = now
+
+	modTime, err := latestModTime(f.certFile, f.keyFile)
+	if err != nil {
+		return f.stale(err)
+	}
+
+	if f.cert != nil && modTime.Equal(f.modTime) {
+		return f.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		// The files may be loaded while they are being replaced.
+		return f.stale(err)
+	}
+
+	f.cert, f.modTime = &cert, modTime
+	return f.cert, nil
+}
+
+func (f *certificateFiles) stale(err error) (*tls.Certificate, error) {
+	if f.cert != nil {
+		return f.cert, nil
+	}
+
+	return nil, err
+}
+
+// latestModTime returns the latest modification time of the files.
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,262 @@
+package pantopoda
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// generateCertificate generates a self-signed certificate with the common
+// name, and returns the PEM blocks of the certificate and its key.
+func generateCertificate(t *testing.T, commonName string) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// newTLSServer starts a TLS server responding the common name of the client
+// certificate, if there is one.
+func newTLSServer(t *testing.T, config *tls.Config) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := ""
+		if len(r.TLS.PeerCertificates) > 0 {
+			name = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"client":%q}`, name)
+	}))
+	srv.TLS = config
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// serverPool returns the root pool trusting the certificate of the server.
+func serverPool(srv *httptest.Server) *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	return pool
+}
+
+// dialServer dials the server for all addresses, so that the server can be
+// requested by the host names its certificate is issued for.
+func dialServer(srv *httptest.Server) Option {
+	return WithDialer(func(ctx context.Context, network string, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, srv.Listener.Addr().String())
+	})
+}
+
+func clientName(t *testing.T, c *Pantopoda, url string) string {
+	t.Helper()
+
+	response, err := c.Get(url, Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body struct {
+		Client string `json:"client"`
+	}
+	if err := response.Unmarshal(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	return body.Client
+}
+
+func TestWithPinsMatch(t *testing.T) {
+	srv := newTLSServer(t, nil)
+	c := NewPantopoda(
+		WithRootCAs(serverPool(srv)),
+		dialServer(srv),
+		WithPins("example.com", SPKIPin(srv.Certificate())),
+	)
+
+	if _, err := c.Get("https://example.com/", Request{}); err != nil {
+		t.Fatalf("expected the pinned certificate to be accepted, got %v", err)
+	}
+}
+
+func TestWithPinsMismatch(t *testing.T) {
+	srv := newTLSServer(t, nil)
+	c := NewPantopoda(
+		WithRootCAs(serverPool(srv)),
+		dialServer(srv),
+		WithPins("example.com", "sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+	)
+
+	_, err := c.Get("https://example.com/", Request{})
+
+	var pinErr PinError
+	if !errors.As(err, &pinErr) {
+		t.Fatalf("expected PinError, got %v", err)
+	}
+	if pinErr.Host != "example.com" || len(pinErr.Pins) == 0 || pinErr.Pins[0] != SPKIPin(srv.Certificate()) {
+		t.Errorf("unexpected pin error %+v", pinErr)
+	}
+}
+
+func TestWithPinsOtherHost(t *testing.T) {
+	srv := newTLSServer(t, nil)
+	c := NewPantopoda(
+		WithRootCAs(serverPool(srv)),
+		dialServer(srv),
+		WithPins("api.example.com", "sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+	)
+
+	if _, err := c.Get("https://example.com/", Request{}); err != nil {
+		t.Fatalf("expected the host without pins to be accepted, got %v", err)
+	}
+}
+
+func TestWithPinsUnverifiedChain(t *testing.T) {
+	// The peer appends the pinned certificate to its own certificate, which
+	// is not signed by it.
+	pinnedPEM, _ := generateCertificate(t, "pinned")
+	leafPEM, leafKeyPEM := generateCertificate(t, "leaf")
+	cert, err := tls.X509KeyPair(append(leafPEM, pinnedPEM...), leafKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(pinnedPEM)
+	pinned, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTLSServer(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+
+	tests := []struct {
+		pin     string
+		matches bool
+	}{
+		{SPKIPin(pinned), false},
+		{SPKIPin(leaf), true},
+	}
+
+	for _, test := range tests {
+		c := NewPantopoda(dialServer(srv), WithPins("example.com", test.pin))
+		c.tlsConfig().InsecureSkipVerify = true
+
+		_, err := c.Get("https://example.com/", Request{})
+
+		var pinErr PinError
+		if test.matches && err != nil {
+			t.Errorf("expected the pinned leaf to be accepted, got %v", err)
+		}
+		if !test.matches && !errors.As(err, &pinErr) {
+			t.Errorf("expected PinError for the unverified chain, got %v", err)
+		}
+	}
+}
+
+func TestWithClientCertificatePEM(t *testing.T) {
+	srv := newTLSServer(t, &tls.Config{ClientAuth: tls.RequireAnyClientCert})
+	certPEM, keyPEM := generateCertificate(t, "client")
+
+	c := NewPantopoda(WithRootCAs(serverPool(srv)), WithClientCertificatePEM(certPEM, keyPEM))
+	if name := clientName(t, c, srv.URL); name != "client" {
+		t.Errorf("expected client certificate %q, got %q", "client", name)
+	}
+
+	c = NewPantopoda(WithRootCAs(serverPool(srv)))
+	if _, err := c.Get(srv.URL, Request{}); err == nil {
+		t.Error("expected the request without client certificate to fail")
+	}
+}
+
+func TestWithClientCertificateRotation(t *testing.T) {
+	srv := newTLSServer(t, &tls.Config{ClientAuth: tls.RequireAnyClientCert})
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+
+	writeCertificate := func(commonName string) {
+		certPEM, keyPEM := generateCertificate(t, commonName)
+		if err := ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeCertificate("client-1")
+	c := NewPantopoda(WithRootCAs(serverPool(srv)), WithClientCertificate(certFile, keyFile))
+	if name := clientName(t, c, srv.URL); name != "client-1" {
+		t.Fatalf("expected client certificate %q, got %q", "client-1", name)
+	}
+
+	// The files are checked for changes at most once per check interval.
+	time.Sleep(certificateCheckInterval + 100*time.Millisecond)
+	writeCertificate("client-2")
+	c.transport.CloseIdleConnections()
+
+	if name := clientName(t, c, srv.URL); name != "client-2" {
+		t.Errorf("expected rotated client certificate %q, got %q", "client-2", name)
+	}
+}
+
+func TestWithTLSVersion(t *testing.T) {
+	srv := newTLSServer(t, &tls.Config{MaxVersion: tls.VersionTLS12})
+
+	c := NewPantopoda(WithRootCAs(serverPool(srv)), WithTLSVersion(tls.VersionTLS13))
+	if _, err := c.Get(srv.URL, Request{}); err == nil {
+		t.Error("expected the request to a TLS 1.2 server to fail")
+	}
+
+	c = NewPantopoda(WithRootCAs(serverPool(srv)), WithTLSVersion(tls.VersionTLS12))
+	if _, err := c.Get(srv.URL, Request{}); err != nil {
+		t.Errorf("expected the request to a TLS 1.2 server to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package pantopoda
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// PayloadValidationError is returned by the client when validation is enabled
+// and the request payload or the decoded response violates validation rules.
+type PayloadValidationError struct {
+	// Outgoing determines that the request payload failed validation, so the
+	// request has not been sent. Otherwise the response failed validation.
+	Outgoing bool
+
+	// ValidationError contains the validation errors.
+	ValidationError ValidationError
+}
+
+func (e PayloadValidationError) Error() string {
+	if e.Outgoing {
+		return fmt.Sprintf("invalid request payload: %s", e.ValidationError)
+	}
+
+	return fmt.Sprintf("invalid response: %s", e.ValidationError)
+}
+
+// Unwrap returns the underlying validation error.
+func (e PayloadValidationError) Unwrap() error {
+	return e.ValidationError
+}
+
+// validatePayload validates the request payload if it is a struct body or a
+// struct implementing RequestBody.
+func validatePayload(payload RequestBody) error {
+	var value interface{} = payload
+	if body, ok := payload.(StructBody); ok {
+		value = body.Value
+	}
+
+	if !isStruct(value) {
+		return nil
+	}
+
+	if validationError := Validate(value); validationError.Failed() {
+		return PayloadValidationError{Outgoing: true, ValidationError: validationError}
+	}
+
+	return nil
+}
+
+// validateResponse validates the value decoded from response if it is a
+// struct.
+func validateResponse(v interface{}) error {
+	if !isStruct(v) {
+		return nil
+	}
+
+	if validationError := Validate(v); validationError.Failed() {
+		return PayloadValidationError{ValidationError: validationError}
+	}
+
+	return nil
+}
+
+// isStruct checks that the value is a struct or a non-nil pointer to struct.
+func isStruct(v interface{}) bool {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	return value.Kind() == reflect.Struct
+}
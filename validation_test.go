@@ -0,0 +1,106 @@
+package pantopoda
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Kamva/pantopoda/schema"
+)
+
+type createUser struct {
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"omitempty,email"`
+}
+
+// newValidationServer starts a server which responds with the body, and counts
+// the requests.
+func newValidationServer(t *testing.T, body string) (*httptest.Server, *int64) {
+	t.Helper()
+
+	hits := new(int64)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(hits, 1)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, hits
+}
+
+func assertPayloadValidationError(t *testing.T, err error, outgoing bool, field string) {
+	t.Helper()
+
+	var validationError PayloadValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("expected a payload validation error, got %v", err)
+	}
+	if validationError.Outgoing != outgoing {
+		t.Errorf("expected the outgoing to be %t", outgoing)
+	}
+	if fields := validationError.ValidationError.Fields(); len(fields) != 1 || fields[0].Field != field {
+		t.Errorf("expected a validation error on %s, got %v", field, fields)
+	}
+}
+
+func TestValidationOutgoing(t *testing.T) {
+	srv, hits := newValidationServer(t, `{}`)
+	c := NewPantopoda(WithValidation())
+
+	_, err := c.Post(srv.URL, Request{Payload: StructBody{Value: createUser{Email: "alice"}}})
+	var validationError PayloadValidationError
+	if !errors.As(err, &validationError) || len(validationError.ValidationError.Fields()) != 2 {
+		t.Fatalf("expected the invalid payload to be rejected, got %v", err)
+	}
+	if atomic.LoadInt64(hits) != 0 {
+		t.Error("expected the invalid payload not to be sent")
+	}
+
+	if _, err = c.Post(srv.URL, Request{Payload: StructBody{Value: &createUser{Name: "alice"}}}); err != nil {
+		t.Errorf("expected the valid payload to be sent, got %v", err)
+	}
+	if _, err = c.Post(srv.URL, Request{Payload: JSONBody{"email": "alice"}}); err != nil {
+		t.Errorf("expected the map payload not to be validated, got %v", err)
+	}
+	if _, err = NewPantopoda().Post(srv.URL, Request{Payload: StructBody{Value: createUser{}}}); err != nil {
+		t.Errorf("expected no validation when it is not enabled, got %v", err)
+	}
+}
+
+func TestValidationResponse(t *testing.T) {
+	srv, _ := newValidationServer(t, `{"email":"alice@example.com"}`)
+
+	response, err := NewPantopoda(WithValidation()).Get(srv.URL, Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var user createUser
+	assertPayloadValidationError(t, response.Unmarshal(&user), false, "name")
+	if user.Email != "alice@example.com" {
+		t.Errorf("expected the response to be decoded, got %+v", user)
+	}
+
+	var value map[string]interface{}
+	if err = response.Unmarshal(&value); err != nil {
+		t.Errorf("expected the map not to be validated, got %v", err)
+	}
+
+	response, _ = NewPantopoda().Get(srv.URL, Request{})
+	if err = response.Unmarshal(&user); err != nil {
+		t.Errorf("expected no validation when it is not enabled, got %v", err)
+	}
+}
+
+func TestValidationResponseSchema(t *testing.T) {
+	srv, _ := newValidationServer(t, `{"name":1}`)
+	s := schema.MustParse([]byte(`{"type":"object","properties":{"name":{"type":"string"}}}`))
+
+	response, err := NewPantopoda().Get(srv.URL, Request{ResponseSchema: s})
+	assertPayloadValidationError(t, err, false, "name")
+	if response.ToString() != `{"name":1}` {
+		t.Errorf("expected the response along with the error, got %s", response.ToString())
+	}
+}